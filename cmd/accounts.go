@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 	"upbank-cli/pkg/api"
 	"upbank-cli/pkg/models"
@@ -14,13 +16,63 @@ import (
 	"golang.org/x/text/message"
 )
 
+// resolveAccount finds the account referred to by ref, which is either an
+// account ID or a display name (case-insensitive).
+func resolveAccount(ctx context.Context, client api.UpClient, ref string) (models.Account, error) {
+	accounts, err := client.GetAccounts(ctx, api.AccountFilter{})
+	if err != nil {
+		return models.Account{}, err
+	}
+
+	var matches []models.Account
+	for _, account := range accounts {
+		if account.ID == ref {
+			return account, nil
+		}
+		if strings.EqualFold(account.Attributes.DisplayName, ref) {
+			matches = append(matches, account)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return models.Account{}, fmt.Errorf("no account with ID or name %q", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return models.Account{}, fmt.Errorf("%d accounts are named %q. Use the account ID instead", len(matches), ref)
+	}
+}
+
+// completeAccountNames offers account display names for shell completion.
+func completeAccountNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	client, err := newClient(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	accounts, err := client.GetAccounts(cmd.Context(), api.AccountFilter{})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var names []string
+	for _, account := range accounts {
+		if strings.HasPrefix(strings.ToLower(account.Attributes.DisplayName), strings.ToLower(toComplete)) {
+			names = append(names, account.Attributes.DisplayName+"\t"+account.Attributes.AccountType)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
 var (
 	accountsCmd = &cobra.Command{
 		Use:   "accounts",
 		Short: "List all accounts",
 		Long:  `List all accounts with their detail with optional filters.`,
+		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			client, err := api.NewClient()
+			client, err := newClient(cmd)
 			if err != nil {
 				return err
 			}
@@ -29,17 +81,18 @@ var (
 			rawMode, _ := cmd.Flags().GetBool("raw")
 			accountType, _ := cmd.Flags().GetString("type")
 			ownershipType, _ := cmd.Flags().GetString("ownership")
+			pageSize, _ := cmd.Flags().GetInt("page-size")
 
-			// Build query parameters
-			params := make(map[string]string)
-			if accountType != "" {
-				params["filter[accountType]"] = accountType
+			filter := api.AccountFilter{
+				AccountType:   strings.ToUpper(accountType),
+				OwnershipType: strings.ToUpper(ownershipType),
+				PageSize:      pageSize,
 			}
-			if ownershipType != "" {
-				params["filter[ownershipType]"] = ownershipType
+			if err := filter.Validate(); err != nil {
+				return err
 			}
 
-			accounts, err := client.GetAccounts(params)
+			accounts, err := client.GetAccounts(cmd.Context(), filter)
 			if err != nil {
 				return err
 			}
@@ -128,7 +181,8 @@ var (
 
 func init() {
 	accountsCmd.Flags().Bool("raw", false, "Display raw numbers without pretty formatting")
-	accountsCmd.Flags().String("type", "", "Filter accounts by type (e.g., SAVER)")
-	accountsCmd.Flags().String("ownership", "", "Filter accounts by ownership type (e.g., INDIVIDUAL)")
+	accountsCmd.Flags().String("type", "", "Filter accounts by type (SAVER, TRANSACTIONAL, HOME_LOAN)")
+	accountsCmd.Flags().String("ownership", "", "Filter accounts by ownership type (INDIVIDUAL, JOINT)")
+	accountsCmd.Flags().Int("page-size", 0, "Number of accounts to request per page (page[size]). Defaults to the Up API default")
 	rootCmd.AddCommand(accountsCmd)
 }
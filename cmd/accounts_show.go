@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"time"
+	"upbank-cli/pkg/models"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	accountsShowCmd = &cobra.Command{
+		Use:   "show <account-id>",
+		Short: "Show a single account",
+		Long:  `Show every detail of a single account, retrieved by its ID.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			rawMode, _ := cmd.Flags().GetBool("raw")
+
+			account, err := client.GetAccount(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			v := newDetailView(cmd.OutOrStdout(), rawMode)
+			attr := account.Attributes
+
+			// Format creation date
+			createdAt := attr.CreatedAt
+			if t, err := time.Parse(time.RFC3339, attr.CreatedAt); err == nil {
+				createdAt = v.Time(t)
+			}
+
+			v.Field("ID", account.ID)
+			v.Field("Name", attr.DisplayName)
+			v.Field("Type", attr.AccountType)
+			v.Field("Ownership", attr.OwnershipType)
+			balance := models.MoneyObject(attr.Balance)
+			v.Field("Balance", v.Money(&balance))
+			v.Field("Created At", createdAt)
+			v.Render()
+			return nil
+		},
+	}
+)
+
+func init() {
+	accountsShowCmd.Flags().Bool("raw", false, "Display raw values without pretty formatting")
+	accountsCmd.AddCommand(accountsShowCmd)
+}
@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"upbank-cli/pkg/api"
+	"upbank-cli/pkg/models"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// attachmentTransactions fetches the transaction of each attachment once.
+type attachmentTransactions struct {
+	client api.UpClient
+	cache  map[string]models.Transaction
+}
+
+func (a *attachmentTransactions) get(ctx context.Context, attachment models.Attachment) (models.Transaction, error) {
+	id := attachment.Relations.Transaction.Data.ID
+	if tx, ok := a.cache[id]; ok {
+		return tx, nil
+	}
+	tx, err := a.client.GetTransaction(ctx, id)
+	if err != nil {
+		return models.Transaction{}, fmt.Errorf("transaction %s of attachment %s: %w", id, attachment.ID, err)
+	}
+	a.cache[id] = tx
+	return tx, nil
+}
+
+// slugify turns a description into something safe to use in a file name.
+func slugify(s string, max int) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimRight(b.String(), "-")
+	if runes := []rune(slug); len(runes) > max {
+		slug = strings.TrimRight(string(runes[:max]), "-")
+	}
+	return slug
+}
+
+// attachmentFileSuffix ends the file name of an attachment with the first
+// eight characters of its ID and its extension, e.g. _b3f2e1c4.jpg. It
+// depends only on the attachment, so it tells whether an attachment has
+// already been downloaded.
+func attachmentFileSuffix(attachment models.Attachment) string {
+	ext := "bin"
+	if attachment.Attributes.FileExtension != nil && *attachment.Attributes.FileExtension != "" {
+		ext = strings.TrimPrefix(*attachment.Attributes.FileExtension, ".")
+	}
+	id := attachment.ID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return "_" + id + "." + ext
+}
+
+// attachmentFileName names an attachment's file after its transaction's
+// date, description and amount, followed by attachmentFileSuffix, e.g.
+// 2024-03-01_Woolworths_-12.34_b3f2e1c4.jpg.
+func attachmentFileName(attachment models.Attachment, tx models.Transaction) string {
+	parts := []string{tx.Attributes.CreatedAt.Format("2006-01-02")}
+	if slug := slugify(tx.Attributes.Description, 40); slug != "" {
+		parts = append(parts, slug)
+	}
+	parts = append(parts, tx.Attributes.Amount.Value)
+	return strings.Join(parts, "_") + attachmentFileSuffix(attachment)
+}
+
+// downloadedFile returns the name of the file in dir an attachment was
+// saved as, or "" if it hasn't been downloaded.
+func downloadedFile(files []os.DirEntry, attachment models.Attachment) string {
+	suffix := attachmentFileSuffix(attachment)
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), suffix) {
+			return f.Name()
+		}
+	}
+	return ""
+}
+
+// downloadAttachment saves an attachment to path. The file is written under
+// a temporary name first, so an interrupted download is never mistaken for a
+// finished one.
+func downloadAttachment(ctx context.Context, client api.UpClient, attachment models.Attachment, path string) (int64, error) {
+	tmp := path + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	n, err := client.DownloadAttachment(ctx, attachment, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return n, nil
+}
+
+var (
+	attachmentsCmd = &cobra.Command{
+		Use:   "attachments",
+		Short: "List transaction attachments",
+		Long: `List the attachments, such as receipts, added to transactions in the Up app,
+together with the transaction each belongs to.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			rawMode, _ := cmd.Flags().GetBool("raw")
+			pageSize, _ := cmd.Flags().GetInt("page-size")
+
+			t := table.NewWriter()
+			t.SetOutputMirror(cmd.OutOrStdout())
+			if rawMode {
+				t.AppendHeader(table.Row{"ID", "Created At", "Type", "Transaction ID", "Transaction Date", "Description", "Amount", "Currency"})
+			} else {
+				t.AppendHeader(table.Row{"ID", "Created At", "Type", "Transaction Date", "Description", "Amount", "Currency"})
+				// Use built-in dark style
+				t.SetStyle(table.StyleColoredRedWhiteOnBlack)
+			}
+
+			txs := &attachmentTransactions{client: client, cache: make(map[string]models.Transaction)}
+			for attachment, err := range client.Attachments(cmd.Context(), pageSize) {
+				if err != nil {
+					return err
+				}
+				tx, err := txs.get(cmd.Context(), attachment)
+				if err != nil {
+					return err
+				}
+
+				layout := "Jan 02, 2006 15:04"
+				if rawMode {
+					layout = time.RFC3339
+				}
+				var createdAt, fileType string
+				if attachment.Attributes.CreatedAt != nil {
+					createdAt = attachment.Attributes.CreatedAt.Format(layout)
+				}
+				if attachment.Attributes.FileContentType != nil {
+					fileType = *attachment.Attributes.FileContentType
+				}
+				txDate := tx.Attributes.CreatedAt.Format(layout)
+
+				if rawMode {
+					t.AppendRow(table.Row{attachment.ID, createdAt, fileType, tx.ID, txDate, tx.Attributes.Description, tx.Attributes.Amount.Value, tx.Attributes.Amount.CurrencyCode})
+				} else {
+					t.AppendRow(table.Row{attachment.ID, createdAt, fileType, txDate, tx.Attributes.Description, tx.Attributes.Amount.Value, tx.Attributes.Amount.CurrencyCode})
+				}
+			}
+
+			t.Render()
+			return nil
+		},
+	}
+
+	attachmentsDownloadCmd = &cobra.Command{
+		Use:   "download [<attachment-id>...]",
+		Short: "Download attachment files",
+		Long: `Download the files of the given attachments, or of every attachment with --all.
+
+Files are named after their transaction's date, description and amount and
+the start of the attachment ID, e.g. 2024-03-01_Woolworths_-12.34_b3f2e1c4.jpg.
+Attachments already saved in the directory are skipped, so running the same
+command again only fetches new attachments.`,
+		Example: `  upbank-cli attachments download <attachment-id>
+  upbank-cli attachments download --all --dir receipts/`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			all, _ := cmd.Flags().GetBool("all")
+			dir, _ := cmd.Flags().GetString("dir")
+
+			if all == (len(args) > 0) {
+				return fmt.Errorf("give either attachment IDs or --all")
+			}
+
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			var attachments []models.Attachment
+			if all {
+				if attachments, err = client.ListAttachments(cmd.Context()); err != nil {
+					return err
+				}
+			} else {
+				for _, id := range args {
+					attachment, err := client.GetAttachment(cmd.Context(), id)
+					if err != nil {
+						return fmt.Errorf("attachment %s: %w", id, err)
+					}
+					attachments = append(attachments, attachment)
+				}
+			}
+			if len(attachments) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No attachments to download")
+				return nil
+			}
+
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+			existing, err := os.ReadDir(dir)
+			if err != nil {
+				return err
+			}
+
+			// Download, carrying on past individual failures
+			txs := &attachmentTransactions{client: client, cache: make(map[string]models.Transaction)}
+			var downloaded, skipped, failed int
+			for _, attachment := range attachments {
+				if downloadedFile(existing, attachment) != "" {
+					skipped++
+					continue
+				}
+				tx, err := txs.get(cmd.Context(), attachment)
+				if err != nil {
+					if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+						return err
+					}
+					fmt.Fprintf(cmd.ErrOrStderr(), "Failed to download %s: %v\n", attachment.ID, err)
+					failed++
+					continue
+				}
+
+				path := filepath.Join(dir, attachmentFileName(attachment, tx))
+				n, err := downloadAttachment(cmd.Context(), client, attachment, path)
+				if err != nil {
+					if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+						return fmt.Errorf("stopped after downloading %d attachments: %w", downloaded, err)
+					}
+					fmt.Fprintf(cmd.ErrOrStderr(), "Failed to download %s: %v\n", attachment.ID, err)
+					failed++
+					continue
+				}
+				downloaded++
+				fmt.Fprintf(cmd.OutOrStdout(), "Downloaded %s (%d bytes)\n", path, n)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Downloaded %d attachments, skipped %d already downloaded\n", downloaded, skipped)
+			if failed > 0 {
+				return fmt.Errorf("%d attachments could not be downloaded", failed)
+			}
+			return nil
+		},
+	}
+)
+
+func init() {
+	attachmentsCmd.Flags().Bool("raw", false, "Display raw values without pretty formatting")
+	attachmentsCmd.Flags().Int("page-size", 0, "Number of attachments to request per page (page[size]). Defaults to the Up API default")
+
+	attachmentsDownloadCmd.Flags().Bool("all", false, "Download every attachment")
+	attachmentsDownloadCmd.Flags().String("dir", ".", "Directory to save files in")
+
+	attachmentsCmd.AddCommand(attachmentsDownloadCmd)
+	rootCmd.AddCommand(attachmentsCmd)
+}
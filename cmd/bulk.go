@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"upbank-cli/pkg/api"
+	"upbank-cli/pkg/models"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// filterFlagNames lists the flags registered by addTransactionFilterFlags.
+var filterFlagNames = []string{"account", "status", "since", "until", "category", "tag", "currency", "description"}
+
+// addBulkFlags registers the flags of commands that change many transactions
+// at once: the transactions come either from stdin or from the transaction
+// filter flags, and are previewed before anything is changed.
+func addBulkFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("bulk", false, "Apply to every transaction matched by the filter flags instead of a single transaction ID")
+	cmd.Flags().Bool("stdin", false, "Apply to the transaction IDs read from stdin, one per line")
+	cmd.Flags().Bool("dry-run", false, "Preview the transactions that would change without changing them")
+	cmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation before changing transactions in bulk")
+	addTransactionFilterFlags(cmd)
+}
+
+// bulkMode reports whether the command was asked to work on many
+// transactions, checking that the bulk flags are used consistently.
+func bulkMode(cmd *cobra.Command) (bool, error) {
+	bulk, _ := cmd.Flags().GetBool("bulk")
+	stdin, _ := cmd.Flags().GetBool("stdin")
+	yes, _ := cmd.Flags().GetBool("yes")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	var filtered bool
+	for _, name := range filterFlagNames {
+		if cmd.Flags().Changed(name) {
+			filtered = true
+		}
+	}
+
+	switch {
+	case bulk && stdin:
+		return false, fmt.Errorf("--bulk and --stdin cannot be used together")
+	case bulk && !filtered:
+		// Refuse to touch every transaction on the account by accident
+		return false, fmt.Errorf("--bulk requires at least one filter flag (e.g. --since, --description)")
+	case filtered && !bulk:
+		return false, fmt.Errorf("filter flags require --bulk")
+	case stdin && !yes && !dryRun:
+		// stdin is taken by the IDs, so there is nothing to read an answer from
+		return false, fmt.Errorf("--stdin requires --yes or --dry-run")
+	}
+	return bulk || stdin, nil
+}
+
+// bulkTransactions returns the transactions selected by --stdin or by the
+// filter flags with --bulk.
+func bulkTransactions(cmd *cobra.Command, client api.UpClient) ([]models.Transaction, error) {
+	if stdin, _ := cmd.Flags().GetBool("stdin"); stdin {
+		ids, err := readIDs(cmd.InOrStdin())
+		if err != nil {
+			return nil, err
+		}
+
+		var transactions []models.Transaction
+		for _, id := range ids {
+			tx, err := client.GetTransaction(cmd.Context(), id)
+			if err != nil {
+				return nil, fmt.Errorf("transaction %s: %w", id, err)
+			}
+			transactions = append(transactions, tx)
+		}
+		return transactions, nil
+	}
+
+	filter, localFilter, err := transactionFiltersFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	seq, err := streamTransactions(cmd, client, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions []models.Transaction
+	for tx, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		if localFilter.matches(tx) {
+			transactions = append(transactions, tx)
+		}
+	}
+	return transactions, nil
+}
+
+// readIDs reads one ID per line, ignoring blank lines and # comments. Only
+// the first field of each line is used, so the first column of other output
+// can be piped in directly.
+func readIDs(r io.Reader) ([]string, error) {
+	var ids []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ids = append(ids, strings.Fields(line)[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading transaction IDs: %v", err)
+	}
+	return ids, nil
+}
+
+// previewTransactions prints the transactions about to change together with
+// the change that will be made to each.
+func previewTransactions(w io.Writer, transactions []models.Transaction, change func(tx models.Transaction) string) {
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.AppendHeader(table.Row{"ID", "Date", "Description", "Amount", "Change"})
+	for _, tx := range transactions {
+		t.AppendRow(table.Row{
+			tx.ID,
+			tx.Attributes.CreatedAt.Format("Jan 02, 2006 15:04"),
+			tx.Attributes.Description,
+			tx.Attributes.Amount.Value,
+			change(tx),
+		})
+	}
+	t.Render()
+}
+
+// confirm asks a yes/no question on stdin unless --yes was given.
+func confirm(cmd *cobra.Command, question string) (bool, error) {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return true, nil
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s [y/N] ", question)
+	answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"upbank-cli/pkg/api"
+	"upbank-cli/pkg/models"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// categoryCacheTTL is how long the cached category list is trusted. Up
+// rarely changes its categories.
+const categoryCacheTTL = 24 * time.Hour
+
+// categoryIndex maps category IDs to categories.
+type categoryIndex map[string]models.Category
+
+func newCategoryIndex(categories []models.Category) categoryIndex {
+	idx := make(categoryIndex, len(categories))
+	for _, category := range categories {
+		idx[category.ID] = category
+	}
+	return idx
+}
+
+// name returns the display name of a category, falling back to its ID for
+// categories missing from the index.
+func (idx categoryIndex) name(id string) string {
+	if category, ok := idx[id]; ok {
+		return category.Attributes.Name
+	}
+	return id
+}
+
+// resolve finds the category referred to by ref, which is either a category
+// ID or a name (case-insensitive). Only child categories can be assigned to
+// transactions, so parent categories are rejected.
+func (idx categoryIndex) resolve(ref string) (models.Category, error) {
+	category, ok := idx[ref]
+	if !ok {
+		var matches []models.Category
+		for _, candidate := range idx {
+			if strings.EqualFold(candidate.Attributes.Name, ref) {
+				matches = append(matches, candidate)
+			}
+		}
+		// Names aren't unique, so don't guess between several matches
+		if len(matches) > 1 {
+			ids := make([]string, len(matches))
+			for i, match := range matches {
+				ids[i] = match.ID
+			}
+			sort.Strings(ids)
+			return models.Category{}, fmt.Errorf("category name %q is ambiguous, it matches: %s. Use the category ID instead", ref, strings.Join(ids, ", "))
+		}
+		if len(matches) == 1 {
+			category, ok = matches[0], true
+		}
+	}
+	if !ok {
+		return models.Category{}, fmt.Errorf("no category with ID or name %q. Run 'upbank-cli categories' to list them", ref)
+	}
+	if category.ParentID() == "" {
+		return models.Category{}, fmt.Errorf("%q is a parent category. Choose one of its child categories", category.Attributes.Name)
+	}
+	return category, nil
+}
+
+// categoryCachePath returns the cache file for the categories of the API at
+// baseURL, so a local stand-in server doesn't pollute the real cache.
+func categoryCachePath(baseURL string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(baseURL))
+	return filepath.Join(dir, "upbank-cli", "categories-"+hex.EncodeToString(sum[:4])+".json"), nil
+}
+
+// usesCassette reports whether the command records or replays a cassette.
+func usesCassette(cmd *cobra.Command) bool {
+	record, _ := cmd.Flags().GetString("record")
+	replay, _ := cmd.Flags().GetString("replay")
+	return record != "" || replay != ""
+}
+
+// loadCategoryIndex returns every category, from the on-disk cache when it is
+// fresh and from the API otherwise. The cache is refreshed after fetching.
+// A cassette must hold the categories it is replayed with, so the cache is
+// neither read nor written while recording or replaying.
+func loadCategoryIndex(cmd *cobra.Command, client api.UpClient, refresh bool) (categoryIndex, error) {
+	path, err := categoryCachePath(client.BaseURL())
+	useCache := err == nil && !usesCassette(cmd)
+	if useCache && !refresh {
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < categoryCacheTTL {
+			if data, err := os.ReadFile(path); err == nil {
+				var categories []models.Category
+				if err := json.Unmarshal(data, &categories); err == nil {
+					return newCategoryIndex(categories), nil
+				}
+			}
+		}
+	}
+
+	categories, err := client.ListCategories(cmd.Context(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Failing to write the cache only costs a request next time
+	if useCache {
+		if data, err := json.Marshal(categories); err == nil {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+				_ = os.WriteFile(path, data, 0o644)
+			}
+		}
+	}
+	return newCategoryIndex(categories), nil
+}
+
+// sortCategoriesByName orders categories by their display name.
+func sortCategoriesByName(categories []models.Category) {
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Attributes.Name < categories[j].Attributes.Name
+	})
+}
+
+var (
+	categoriesCmd = &cobra.Command{
+		Use:   "categories",
+		Short: "List all categories",
+		Long:  `List all transaction categories as a tree of parent categories and their children, with the IDs accepted by --category.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			// Get flag values
+			rawMode, _ := cmd.Flags().GetBool("raw")
+			parent, _ := cmd.Flags().GetString("parent")
+
+			// Group children under their parents
+			var parents []models.Category
+			children := make(map[string][]models.Category)
+			if parent != "" {
+				p, err := client.GetCategory(cmd.Context(), parent)
+				if err != nil {
+					return err
+				}
+				kids, err := client.ListCategories(cmd.Context(), parent)
+				if err != nil {
+					return err
+				}
+				parents = append(parents, p)
+				children[p.ID] = kids
+			} else {
+				// Listing everything doubles as a refresh of the cache
+				idx, err := loadCategoryIndex(cmd, client, true)
+				if err != nil {
+					return err
+				}
+				for _, category := range idx {
+					if parentID := category.ParentID(); parentID != "" {
+						children[parentID] = append(children[parentID], category)
+					} else {
+						parents = append(parents, category)
+					}
+				}
+			}
+			sortCategoriesByName(parents)
+
+			t := table.NewWriter()
+			t.SetOutputMirror(cmd.OutOrStdout())
+
+			// Set header based on mode
+			if rawMode {
+				t.AppendHeader(table.Row{"ID", "Name", "Parent"})
+			} else {
+				t.AppendHeader(table.Row{"Category", "ID"})
+				t.SetStyle(table.StyleColoredRedWhiteOnBlack)
+			}
+
+			for _, p := range parents {
+				if rawMode {
+					t.AppendRow(table.Row{p.ID, p.Attributes.Name, ""})
+				} else {
+					t.AppendRow(table.Row{p.Attributes.Name, p.ID})
+				}
+
+				kids := children[p.ID]
+				sortCategoriesByName(kids)
+				for i, child := range kids {
+					if rawMode {
+						t.AppendRow(table.Row{child.ID, child.Attributes.Name, p.ID})
+						continue
+					}
+					branch := "├─ "
+					if i == len(kids)-1 {
+						branch = "└─ "
+					}
+					t.AppendRow(table.Row{branch + child.Attributes.Name, child.ID})
+				}
+			}
+
+			t.Render()
+			return nil
+		},
+	}
+)
+
+func init() {
+	categoriesCmd.Flags().Bool("raw", false, "Display a flat list of categories without pretty formatting")
+	categoriesCmd.Flags().String("parent", "", "Only show the children of this parent category ID (e.g. good-life)")
+	rootCmd.AddCommand(categoriesCmd)
+}
@@ -0,0 +1,480 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"upbank-cli/pkg/api"
+	"upbank-cli/pkg/models"
+	"upbank-cli/pkg/uptest"
+	"upbank-cli/pkg/webhook"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// resetFlags restores every flag of cmd and its subcommands to its default,
+// as the commands are package globals shared by every run.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if !f.Changed {
+			return
+		}
+		if v, ok := f.Value.(pflag.SliceValue); ok {
+			_ = v.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, c := range cmd.Commands() {
+		resetFlags(c)
+	}
+}
+
+// run executes the CLI with args against srv and returns what it wrote to
+// stdout.
+func run(t *testing.T, srv *uptest.Server, args ...string) (string, error) {
+	t.Helper()
+	SetClientFactory(func(*cobra.Command) (api.UpClient, error) {
+		return api.NewClient(api.WithBaseURL(srv.BaseURL()), api.WithToken(uptest.DefaultToken, "test"))
+	})
+	t.Cleanup(func() { SetClientFactory(nil) })
+	return execute(t, args...)
+}
+
+// execute runs the CLI with args, isolated from the user's environment, and
+// returns what it wrote to stdout.
+func execute(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	t.Setenv("UPBANK_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	for _, name := range api.TokenEnvVars {
+		t.Setenv(name, "")
+	}
+	resetFlags(rootCmd)
+
+	var stdout, stderr bytes.Buffer
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stderr)
+	rootCmd.SetIn(strings.NewReader(""))
+	rootCmd.SetArgs(args)
+	err := rootCmd.ExecuteContext(context.Background())
+	cancelTimeout()
+	if stderr.Len() > 0 {
+		t.Logf("stderr of %v:\n%s", args, stderr.String())
+	}
+	return stdout.String(), err
+}
+
+// runJSON runs a command printing transactions with -o json and returns
+// their IDs.
+func runJSON(t *testing.T, srv *uptest.Server, args ...string) []string {
+	t.Helper()
+	out, err := run(t, srv, append(args, "-o", "json")...)
+	if err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+	var transactions []models.Transaction
+	if err := json.Unmarshal([]byte(out), &transactions); err != nil {
+		t.Fatalf("%v: decoding output: %v\n%s", args, err, out)
+	}
+	var ids []string
+	for _, tx := range transactions {
+		ids = append(ids, tx.ID)
+	}
+	return ids
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"other error", errors.New("boom"), exitError},
+		{"unauthorized", &api.Error{StatusCode: http.StatusUnauthorized}, exitAuth},
+		{"forbidden", &api.Error{StatusCode: http.StatusForbidden}, exitAuth},
+		{"bad request", &api.Error{StatusCode: http.StatusBadRequest}, exitValidation},
+		{"unprocessable", &api.Error{StatusCode: http.StatusUnprocessableEntity}, exitValidation},
+		{"invalid filter", api.TransactionFilter{Status: "PENDING"}.Validate(), exitValidation},
+		{"rate limited", &api.Error{StatusCode: http.StatusTooManyRequests}, exitRateLimited},
+		{"server error", &api.Error{StatusCode: http.StatusBadGateway}, exitServer},
+		{"not found", &api.Error{StatusCode: http.StatusNotFound}, exitError},
+		{"wrapped", fmt.Errorf("page 3: %w", &api.Error{StatusCode: http.StatusServiceUnavailable}), exitServer},
+		{"interrupted", fmt.Errorf("stopped after 40 transactions: %w", context.Canceled), exitInterrupted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	srv := uptest.NewServer(uptest.DefaultFixtures())
+	defer srv.Close()
+
+	got := runJSON(t, srv, "transactions", "--since", "2024-01-03T00:00:00+11:00", "--until", "2024-01-08T00:00:00+11:00")
+	if want := []string{"tx-04", "tx-03", "tx-02"}; !slices.Equal(got, want) {
+		t.Errorf("transactions = %v, want %v", got, want)
+	}
+
+	got = runJSON(t, srv, "transactions", "--account", "Rainy Day")
+	if want := []string{"tx-transfer"}; !slices.Equal(got, want) {
+		t.Errorf("transactions --account = %v, want %v", got, want)
+	}
+
+	// Flags from the previous run must not leak into this one
+	got = runJSON(t, srv, "transactions", "--currency", "JPY")
+	if want := []string{"tx-13"}; !slices.Equal(got, want) {
+		t.Errorf("transactions --currency = %v, want %v", got, want)
+	}
+
+	out, err := run(t, srv, "transactions", "--limit", "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Coles", "Pubs & Bars", "Opal Travel"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("table output is missing %q:\n%s", want, out)
+		}
+	}
+
+	_, err = run(t, srv, "transactions", "--page-size", "101")
+	if code := exitCode(err); code != exitValidation {
+		t.Errorf("--page-size 101: exit code %d (%v), want %d", code, err, exitValidation)
+	}
+}
+
+func TestMisspelledSubcommand(t *testing.T) {
+	srv := uptest.NewServer(uptest.DefaultFixtures())
+	defer srv.Close()
+
+	for _, args := range [][]string{
+		{"transactions", "categorise", "tx-01", "groceries"},
+		{"accounts", "shwo", "acc-spending"},
+	} {
+		_, err := run(t, srv, args...)
+		if err == nil || !strings.Contains(err.Error(), "unknown command") {
+			t.Errorf("%v: error = %v, want unknown command", args, err)
+		}
+	}
+	if got := srv.Requests(); len(got) != 0 {
+		t.Errorf("misspelled subcommands sent requests: %v", got)
+	}
+}
+
+func TestCategorize(t *testing.T) {
+	srv := uptest.NewServer(uptest.DefaultFixtures())
+	defer srv.Close()
+
+	out, err := run(t, srv, "transactions", "categorize", "tx-01", "Pubs & Bars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Categorized 1 of 1 transactions as Pubs & Bars") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if tx, _ := srv.Transaction("tx-01"); transactionCategory(tx) != "pubs-and-bars" || transactionParentCategory(tx) != "good-life" {
+		t.Errorf("tx-01 is in %s/%s, want good-life/pubs-and-bars", transactionParentCategory(tx), transactionCategory(tx))
+	}
+
+	if _, err := run(t, srv, "transactions", "categorize", "tx-01", "--clear"); err != nil {
+		t.Fatal(err)
+	}
+	if tx, _ := srv.Transaction("tx-01"); transactionCategory(tx) != "" {
+		t.Errorf("tx-01 is still in %s after --clear", transactionCategory(tx))
+	}
+
+	// A dry run changes nothing
+	out, err = run(t, srv, "transactions", "categorize", "--bulk", "--description", "opal", "--dry-run", "groceries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Dry run: 4 transactions would be changed") {
+		t.Errorf("unexpected dry run output:\n%s", out)
+	}
+	if tx, _ := srv.Transaction("tx-03"); transactionCategory(tx) != "public-transport" {
+		t.Errorf("dry run changed tx-03 to %s", transactionCategory(tx))
+	}
+
+	out, err = run(t, srv, "transactions", "categorize", "--bulk", "--description", "opal", "--yes", "groceries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Categorized 4 of 4 transactions as Groceries") {
+		t.Errorf("unexpected bulk output:\n%s", out)
+	}
+	for _, id := range []string{"tx-03", "tx-08", "tx-18", "tx-23"} {
+		if tx, _ := srv.Transaction(id); transactionCategory(tx) != "groceries" {
+			t.Errorf("%s is in %s, want groceries", id, transactionCategory(tx))
+		}
+	}
+}
+
+func TestCategoryCacheWithCassette(t *testing.T) {
+	srv := uptest.NewServer(uptest.DefaultFixtures())
+	defer srv.Close()
+	client, err := api.NewClient(api.WithBaseURL(srv.BaseURL()), api.WithToken(uptest.DefaultToken, "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A warm cache that disagrees with the server
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	path, err := categoryCachePath(client.BaseURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached := []byte(`[{"type":"categories","id":"cached-only","attributes":{"name":"Cached Only"}}]`)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, cached, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, flag := range []string{"", "record", "replay"} {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("record", "", "")
+		cmd.Flags().String("replay", "", "")
+		cmd.SetContext(context.Background())
+		if flag != "" {
+			cmd.Flags().Set(flag, t.TempDir())
+		}
+		idx, err := loadCategoryIndex(cmd, client, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, fromCache := idx["cached-only"]; fromCache != (flag == "") {
+			t.Errorf("with %q set, categories came from the cache = %v", flag, fromCache)
+		}
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, cached) {
+		t.Errorf("cache was rewritten while using a cassette:\n%s", data)
+	}
+}
+
+func TestTagsMerge(t *testing.T) {
+	srv := uptest.NewServer(uptest.DefaultFixtures())
+	defer srv.Close()
+
+	_, err := run(t, srv, "tags", "merge", "holiday", "work", "--into", "work", "--dry-run")
+	if err == nil || !strings.Contains(err.Error(), "cannot be merged into itself") {
+		t.Errorf("merging a tag into itself: error = %v", err)
+	}
+
+	out, err := run(t, srv, "tags", "merge", "holiday", "holiday", "--into", "trips", "--dry-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out, "holiday"); n != 3 {
+		t.Errorf("holiday appears %d times, want once in the counts and once per transaction:\n%s", n, out)
+	}
+	if !strings.Contains(out, "Dry run: 2 transactions would be retagged as trips") {
+		t.Errorf("unexpected dry run report:\n%s", out)
+	}
+
+	if _, err := run(t, srv, "tags", "merge", "holiday", "holiday", "--into", "trips", "--yes"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"tx-17", "tx-06"} {
+		tx, _ := srv.Transaction(id)
+		var tags []string
+		for _, tag := range tx.Relations.Tags.Data {
+			tags = append(tags, tag.ID)
+		}
+		if !slices.Contains(tags, "trips") || slices.Contains(tags, "holiday") {
+			t.Errorf("%s tags = %v, want trips instead of holiday", id, tags)
+		}
+	}
+}
+
+func TestTransactionsParallel(t *testing.T) {
+	srv := uptest.NewServer(uptest.DefaultFixtures())
+	defer srv.Close()
+
+	since, _ := time.Parse(time.RFC3339, "2024-01-01T00:00:00+11:00")
+	until, _ := time.Parse(time.RFC3339, "2024-03-01T00:00:00+11:00")
+	args := []string{"transactions", "--since", since.Format(time.RFC3339), "--until", until.Format(time.RFC3339), "--page-size", "4"}
+
+	// Put a transaction exactly on the bound between two of the windows, so
+	// both return it
+	windows, err := api.SplitWindows(api.TransactionFilter{Since: since, Until: until}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.AddTransaction(uptest.NewTransaction(uptest.TransactionSpec{
+		ID: "tx-boundary", Account: "acc-spending", Description: "On the bound", Amount: "-1.00", CreatedAt: windows[2].Since,
+	}))
+
+	sequential := runJSON(t, srv, args...)
+	if len(sequential) != 28 || !slices.Contains(sequential, "tx-boundary") {
+		t.Fatalf("sequential fetch returned %d transactions, want 28 including tx-boundary", len(sequential))
+	}
+	for _, windows := range []string{"2", "5", "16"} {
+		parallel := runJSON(t, srv, append(args, "--parallel", windows, "--parallel-workers", "3")...)
+		if !slices.Equal(parallel, sequential) {
+			t.Errorf("--parallel %s = %v, want %v", windows, parallel, sequential)
+		}
+		if n := len(slices.DeleteFunc(parallel, func(id string) bool { return id != "tx-boundary" })); n != 1 {
+			t.Errorf("--parallel %s printed tx-boundary %d times, want once", windows, n)
+		}
+	}
+
+	if _, err := run(t, srv, "transactions", "--parallel", "4"); err == nil {
+		t.Error("--parallel without --since succeeded")
+	}
+}
+
+func TestAttachmentsDownload(t *testing.T) {
+	// A second receipt for the same transaction, listed before the first
+	fixtures := uptest.DefaultFixtures()
+	receipt := fixtures.Attachments[0]
+	second := receipt
+	second.ID = "b3f2e1c4-second"
+	second.Data = []byte("\xff\xd8 second receipt \xff\xd9")
+	fixtures.Attachments = append([]uptest.File{second}, fixtures.Attachments...)
+	srv := uptest.NewServer(fixtures)
+	defer srv.Close()
+	dir := t.TempDir()
+
+	if _, err := run(t, srv, "attachments", "download", receipt.ID, "--dir", dir); err != nil {
+		t.Fatal(err)
+	}
+	out, err := run(t, srv, "attachments", "download", "--all", "--dir", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Downloaded 1 attachments, skipped 1 already downloaded") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	for _, f := range []uptest.File{receipt, second} {
+		files, _ := filepath.Glob(filepath.Join(dir, "*_"+f.ID[:8]+".jpg"))
+		if len(files) != 1 {
+			t.Errorf("files of %s = %v, want one", f.ID, files)
+			continue
+		}
+		if data, _ := os.ReadFile(files[0]); !bytes.Equal(data, f.Data) {
+			t.Errorf("%s holds %q, want %q", files[0], data, f.Data)
+		}
+	}
+
+	out, err = run(t, srv, "attachments", "download", "--all", "--dir", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Downloaded 0 attachments, skipped 2 already downloaded") {
+		t.Errorf("unexpected output of the second run:\n%s", out)
+	}
+}
+
+func TestSetClientFactory(t *testing.T) {
+	srv := uptest.NewServer(uptest.DefaultFixtures())
+	defer srv.Close()
+
+	if _, err := run(t, srv, "accounts"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.ContainsFunc(srv.Requests(), func(r string) bool { return strings.HasPrefix(r, "GET /api/v1/accounts") }) {
+		t.Errorf("the factory's client was not used: %v", srv.Requests())
+	}
+
+	// doctor goes through the factory as well
+	out, err := run(t, srv, "doctor", "-o", "json")
+	if err != nil {
+		t.Fatalf("doctor: %v\n%s", err, out)
+	}
+	var report doctorReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK || !report.TokenAccepted || report.APIURL != srv.BaseURL() || report.TokenSource != "test" {
+		t.Errorf("doctor report = %+v, want the fake server's client", report)
+	}
+
+	// nil restores the default factory, which needs a token
+	SetClientFactory(nil)
+	if client, err := newClient(rootCmd); !errors.Is(err, api.ErrNoToken) || client != nil {
+		t.Errorf("default factory without a token = %v, %v; want ErrNoToken", client, err)
+	}
+
+	// doctor still checks the connection without a token
+	out, err = execute(t, "doctor", "-o", "json", "--api-url", srv.BaseURL())
+	if err == nil {
+		t.Fatal("doctor without a token succeeded")
+	}
+	report = doctorReport{}
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatal(err)
+	}
+	var checks []string
+	for _, c := range report.Checks {
+		checks = append(checks, c.Name+"="+c.Status)
+	}
+	for _, want := range []string{"API token=fail", "Connectivity=ok"} {
+		if !slices.Contains(checks, want) {
+			t.Errorf("doctor checks = %v, want %s", checks, want)
+		}
+	}
+}
+
+func TestSetWebhookHTTPClient(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := writeSecretFile(secretFile, "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deliver to an in-process receiver rather than over the network
+	var received []models.WebhookEvent
+	receiver := webhook.Handler([]byte("s3cret"), func(_ context.Context, event models.WebhookEvent) error {
+		received = append(received, event)
+		return nil
+	})
+	SetWebhookHTTPClient(&http.Client{Transport: handlerTransport{receiver}})
+	defer SetWebhookHTTPClient(nil)
+
+	out, err := execute(t, "webhooks", "send", "--to", "http://receiver.invalid/hook", "--secret-file", secretFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 1 || received[0].Attributes.EventType != models.EventPing {
+		t.Fatalf("received %+v, want one PING", received)
+	}
+	if !strings.Contains(out, "Sent PING event "+received[0].ID) {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	// The default client doesn't wait forever on a silent receiver
+	SetWebhookHTTPClient(nil)
+	if webhookHTTPClient.Timeout != api.DefaultRequestTimeout {
+		t.Errorf("default webhook client timeout = %s, want %s", webhookHTTPClient.Timeout, api.DefaultRequestTimeout)
+	}
+}
+
+// handlerTransport answers requests with an http.Handler.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.handler.ServeHTTP(rec, req)
+	return rec.Result(), nil
+}
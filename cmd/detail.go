@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"io"
+	"time"
+	"upbank-cli/pkg/models"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// detailView renders a single resource as a vertical list of field/value
+// rows.
+type detailView struct {
+	t       table.Writer
+	p       *message.Printer
+	rawMode bool
+}
+
+func newDetailView(w io.Writer, rawMode bool) *detailView {
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.AppendHeader(table.Row{"Field", "Value"})
+
+	// Use built-in dark style
+	if !rawMode {
+		t.SetStyle(table.StyleColoredRedWhiteOnBlack)
+	}
+
+	return &detailView{
+		t:       t,
+		p:       message.NewPrinter(language.English),
+		rawMode: rawMode,
+	}
+}
+
+// Field appends a row. Empty values are skipped unless in raw mode, so the
+// pretty view only lists what the resource actually has.
+func (v *detailView) Field(name, value string) {
+	if value == "" && !v.rawMode {
+		return
+	}
+	v.t.AppendRow(table.Row{name, value})
+}
+
+// Money formats an amount with its currency, with thousand separators
+// unless in raw mode.
+func (v *detailView) Money(m *models.MoneyObject) string {
+	if m == nil {
+		return ""
+	}
+	if v.rawMode {
+		return m.Value + " " + m.CurrencyCode
+	}
+	return v.p.Sprintf("%.2f %s", float64(m.ValueInBaseUnits)/100.0, m.CurrencyCode)
+}
+
+// Time formats a timestamp, in RFC3339 when in raw mode. Zero times, such as
+// the settlement date of a held transaction, are left empty.
+func (v *detailView) Time(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	if v.rawMode {
+		return t.Format(time.RFC3339)
+	}
+	return t.Format("Jan 02, 2006 15:04")
+}
+
+func (v *detailView) Render() {
+	v.t.Render()
+}
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"upbank-cli/pkg/api"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// maxClockSkew is the clock difference with Up above which doctor warns.
+// Date filters and webhook timestamps are compared against the local clock.
+const maxClockSkew = time.Minute
+
+// Check statuses reported by doctor
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// connectionInfo is implemented by clients that talk HTTP to Up themselves,
+// such as *api.Client, so doctor can report how they connect.
+type connectionInfo interface {
+	TokenSource() string
+	Proxy() (*url.URL, error)
+}
+
+// doctorCheck is the outcome of a single doctor check.
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// doctorReport is the machine-readable output of doctor.
+type doctorReport struct {
+	OK            bool          `json:"ok"`
+	ConfigFile    string        `json:"configFile"`
+	APIURL        string        `json:"apiUrl"`
+	TokenSource   string        `json:"tokenSource,omitempty"`
+	TokenAccepted bool          `json:"tokenAccepted"`
+	Proxy         string        `json:"proxy,omitempty"`
+	LatencyMS     int64         `json:"latencyMs,omitempty"`
+	ClockSkewMS   *int64        `json:"clockSkewMs,omitempty"`
+	Checks        []doctorCheck `json:"checks"`
+
+	pingErr error
+}
+
+func (r *doctorReport) add(name, status, format string, args ...any) {
+	r.Checks = append(r.Checks, doctorCheck{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+}
+
+// failed returns the number of failed checks.
+func (r *doctorReport) failed() int {
+	var n int
+	for _, c := range r.Checks {
+		if c.Status == checkFail {
+			n++
+		}
+	}
+	return n
+}
+
+// runDoctor performs every check. Only errors that prevent checking at all
+// are returned; everything else is recorded in the report.
+func runDoctor(cmd *cobra.Command) (*doctorReport, error) {
+	r := &doctorReport{ConfigFile: cfgPath}
+
+	// Config file
+	if _, err := os.Stat(cfgPath); err == nil {
+		r.add("Config file", checkOK, "%s", cfgPath)
+	} else {
+		r.add("Config file", checkOK, "%s (not found, using defaults)", cfgPath)
+	}
+
+	// Without a token Up still answers, so connectivity can be checked
+	client, tokenMissing, err := newClientWithoutToken(cmd)
+	if err != nil {
+		return nil, err
+	}
+	info, hasInfo := client.(connectionInfo)
+
+	// Token source
+	switch {
+	case tokenMissing:
+		r.add("API token", checkFail, "not set. Export %s or set \"api-token\" in the config file", api.TokenEnvVars[0])
+	case !hasInfo:
+		r.add("API token", checkOK, "provided by a custom client")
+	default:
+		r.TokenSource = info.TokenSource()
+		r.add("API token", checkOK, "from %s", info.TokenSource())
+
+		// Both names set to different tokens is a likely source of confusion
+		var set []string
+		for _, name := range api.TokenEnvVars {
+			if os.Getenv(name) != "" {
+				set = append(set, name)
+			}
+		}
+		if len(set) > 1 && os.Getenv(set[0]) != os.Getenv(set[1]) {
+			r.add("API token", checkWarn, "%s and %s are both set to different tokens; %s is used", set[0], set[1], set[0])
+		}
+	}
+
+	// API URL
+	r.APIURL = client.BaseURL()
+	if u, err := url.Parse(client.BaseURL()); err != nil || u.Host == "" {
+		r.add("API URL", checkFail, "%s is not a valid URL", client.BaseURL())
+	} else if client.BaseURL() != api.DefaultBaseURL {
+		r.add("API URL", checkWarn, "%s (not the default %s)", client.BaseURL(), api.DefaultBaseURL)
+	} else {
+		r.add("API URL", checkOK, "%s", client.BaseURL())
+	}
+
+	// Proxy
+	proxyFlag, _ := cmd.Flags().GetString("proxy")
+	var proxy *url.URL
+	if hasInfo {
+		proxy, err = info.Proxy()
+	}
+	switch {
+	case !hasInfo:
+		r.add("Proxy", checkOK, "unknown, the client does not report it")
+	case err != nil:
+		r.add("Proxy", checkFail, "invalid proxy setting: %v", err)
+	case proxy == nil:
+		r.add("Proxy", checkOK, "none, connecting directly")
+	case proxyFlag != "":
+		r.Proxy = proxy.Redacted()
+		r.add("Proxy", checkOK, "%s (from --proxy or config file)", proxy.Redacted())
+	default:
+		r.Proxy = proxy.Redacted()
+		r.add("Proxy", checkOK, "%s (from environment)", proxy.Redacted())
+	}
+
+	// Connectivity and token
+	ping, err := client.Ping(cmd.Context())
+	r.LatencyMS = ping.Latency.Milliseconds()
+	var apiErr *api.Error
+	switch {
+	case err == nil:
+		r.TokenAccepted = true
+		r.add("Connectivity", checkOK, "reached Up in %s", ping.Latency.Round(time.Millisecond))
+		r.add("Token accepted", checkOK, "ping %s %s", ping.ID, ping.StatusEmoji)
+	case errors.As(err, &apiErr) && apiErr.IsAuthError():
+		r.add("Connectivity", checkOK, "reached Up in %s", ping.Latency.Round(time.Millisecond))
+		if !tokenMissing {
+			r.add("Token accepted", checkFail, "token rejected: %v", err)
+			r.pingErr = err
+		}
+	case errors.As(err, &apiErr):
+		r.add("Connectivity", checkFail, "Up responded with an error: %v", err)
+		r.pingErr = err
+	default:
+		r.add("Connectivity", checkFail, "%v", err)
+		r.pingErr = err
+	}
+
+	// Clock skew, allowing for the time the response took to arrive
+	if !ping.ServerTime.IsZero() {
+		skew := time.Since(ping.ServerTime) - ping.Latency/2
+		ms := skew.Milliseconds()
+		r.ClockSkewMS = &ms
+		// The Date header only has second precision
+		if skew.Abs() > maxClockSkew {
+			r.add("Clock", checkWarn, "local clock is %s off Up's", skew.Round(time.Second))
+		} else {
+			r.add("Clock", checkOK, "within %s of Up's", max(skew.Abs(), time.Second).Round(time.Second))
+		}
+	}
+
+	r.OK = r.failed() == 0
+	return r, nil
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the API token and connection to Up",
+	Long: `Check that upbank-cli is set up correctly: where the API token comes from and
+whether Up accepts it, the API URL and proxy in use, and how far the local
+clock is off Up's.
+
+With -o json the report is printed as a JSON object for use in scripts. The
+exit status is non-zero when a check fails.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		if output != "table" && output != "json" {
+			return fmt.Errorf("invalid output format %q. Use table or json", output)
+		}
+
+		r, err := runDoctor(cmd)
+		if err != nil {
+			return err
+		}
+
+		if output == "json" {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		} else {
+			t := table.NewWriter()
+			t.SetOutputMirror(cmd.OutOrStdout())
+			t.AppendHeader(table.Row{"Check", "Status", "Detail"})
+			// Use built-in dark style
+			t.SetStyle(table.StyleColoredRedWhiteOnBlack)
+			for _, c := range r.Checks {
+				t.AppendRow(table.Row{c.Name, strings.ToUpper(c.Status), c.Detail})
+			}
+			t.Render()
+		}
+
+		if n := r.failed(); n > 0 {
+			if r.pingErr != nil {
+				// Keep the exit status of the API error, e.g. 3 for a bad token
+				return fmt.Errorf("%d checks failed: %w", n, r.pingErr)
+			}
+			return fmt.Errorf("%d checks failed", n)
+		}
+		return nil
+	},
+}
+
+func init() {
+	doctorCmd.Flags().StringP("output", "o", "table", "Output format: table or json")
+	rootCmd.AddCommand(doctorCmd)
+}
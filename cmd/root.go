@@ -1,23 +1,261 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
+	"upbank-cli/pkg/api"
+	"upbank-cli/pkg/config"
 
 	"github.com/spf13/cobra"
 )
 
+// Process exit codes, so scripts can tell failure modes apart.
+const (
+	exitError       = 1
+	exitAuth        = 3
+	exitValidation  = 4
+	exitRateLimited = 5
+	exitServer      = 6
+	exitInterrupted = 130
+)
+
+// parallelRateLimit is the requests per second --parallel is held to unless
+// --rate-limit is given on the command line or in the config file.
+const parallelRateLimit = 10
+
+// cancelTimeout releases the overall deadline installed by the root
+// command's PersistentPreRunE once the command has finished.
+var cancelTimeout context.CancelFunc = func() {}
+
+// cfg is the config file loaded before every command runs, for commands
+// that read structured settings from it.
+var cfg config.File
+
+// cfgPath is the path cfg was loaded from. The file may not exist.
+var cfgPath string
+
 var rootCmd = &cobra.Command{
 	Use:   "upbank-cli",
 	Short: "A CLI tool to interact with Upbank API",
 	Long: `A CLI tool that allows you to interact with Upbank API to:
 - List and retrieve account information
 - Find and list transactions with total amounts`,
+	// Errors are reported by Execute so they can be mapped to exit codes
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Arguments parsed fine, so later failures shouldn't print usage
+		cmd.SilenceUsage = true
+
+		// Fill in global flags not given on the command line from the
+		// config file. Only the root's persistent flags are settings; a key
+		// like "yes" or "output" must not reach a command's own flags.
+		path, _ := cmd.Flags().GetString("config")
+		if path == "" {
+			var err error
+			if path, err = config.DefaultPath(); err != nil {
+				return err
+			}
+		}
+		loaded, err := config.Load(path)
+		if err != nil {
+			return err
+		}
+		if err := loaded.ApplyFlags(cmd.Root().PersistentFlags()); err != nil {
+			return err
+		}
+		cfg, cfgPath = loaded, path
+
+		// Apply the overall deadline to the whole command run
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			cancelTimeout = cancel
+			cmd.SetContext(ctx)
+		}
+		return nil
+	},
+}
+
+// ClientFactory creates the API client a command talks to.
+type ClientFactory func(cmd *cobra.Command) (api.UpClient, error)
+
+// clientFactory is used by every command to create its client.
+var clientFactory ClientFactory = defaultClient
+
+// SetClientFactory replaces how commands create their API client, e.g. to
+// run them against a fake server, a cached backend or several profiles at
+// once. A nil factory restores the default.
+func SetClientFactory(f ClientFactory) {
+	if f == nil {
+		f = defaultClient
+	}
+	clientFactory = f
+}
+
+// newClient returns the API client for a command, from the client factory.
+func newClient(cmd *cobra.Command) (api.UpClient, error) {
+	return clientFactory(cmd)
+}
+
+// placeholderTokenKey is the context key set by newClientWithoutToken.
+type placeholderTokenKey struct{}
+
+// newClientWithoutToken returns the API client for a command like newClient,
+// but lets the default factory fall back to a placeholder token when none is
+// set, for commands such as doctor that are useful without one. It reports
+// whether the token is missing.
+func newClientWithoutToken(cmd *cobra.Command) (api.UpClient, bool, error) {
+	client, err := newClient(cmd)
+	if !errors.Is(err, api.ErrNoToken) {
+		return client, false, err
+	}
+	ctx := cmd.Context()
+	cmd.SetContext(context.WithValue(ctx, placeholderTokenKey{}, true))
+	defer cmd.SetContext(ctx)
+	client, err = newClient(cmd)
+	return client, true, err
+}
+
+// defaultClient builds an API client configured from the root command's
+// persistent flags.
+func defaultClient(cmd *cobra.Command) (api.UpClient, error) {
+	opts, err := clientOptions(cmd)
+	if err != nil {
+		return nil, err
+	}
+	client, err := api.NewClient(opts...)
+	if errors.Is(err, api.ErrNoToken) && cmd.Context() != nil && cmd.Context().Value(placeholderTokenKey{}) != nil {
+		client, err = api.NewClient(append(opts, api.WithToken("missing", ""))...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// clientOptions returns the client options set by the root command's
+// persistent flags and the config file.
+func clientOptions(cmd *cobra.Command) ([]api.Option, error) {
+	requestTimeout, _ := cmd.Flags().GetDuration("request-timeout")
+	retries, _ := cmd.Flags().GetInt("retries")
+	retryDelay, _ := cmd.Flags().GetDuration("retry-delay")
+	retryMaxDelay, _ := cmd.Flags().GetDuration("retry-max-delay")
+	retryBudget, _ := cmd.Flags().GetInt("retry-budget")
+	rateLimit, _ := cmd.Flags().GetFloat64("rate-limit")
+	apiURL, _ := cmd.Flags().GetString("api-url")
+	caBundle, _ := cmd.Flags().GetString("ca-bundle")
+	proxy, _ := cmd.Flags().GetString("proxy")
+	record, _ := cmd.Flags().GetString("record")
+	replay, _ := cmd.Flags().GetString("replay")
+	debug, _ := cmd.Flags().GetBool("debug")
+	dumpBodies, _ := cmd.Flags().GetString("dump-bodies")
+
+	// Only concurrent fetching can burst past what Up tolerates, so it is
+	// the only thing throttled by default
+	_, rateLimitConfigured := cfg["rate-limit"]
+	if parallel, _ := cmd.Flags().GetInt("parallel"); parallel > 1 && !cmd.Flags().Changed("rate-limit") && !rateLimitConfigured {
+		rateLimit = parallelRateLimit
+	}
+
+	opts := []api.Option{
+		api.WithRequestTimeout(requestTimeout),
+		api.WithRetryPolicy(api.RetryPolicy{
+			MaxRetries: retries,
+			BaseDelay:  retryDelay,
+			MaxDelay:   retryMaxDelay,
+			Budget:     retryBudget,
+		}),
+		api.WithRateLimit(rateLimit),
+		api.WithCABundle(caBundle),
+		api.WithProxy(proxy),
+		api.WithRecorder(record),
+		api.WithReplay(replay),
+		api.WithBodyDump(dumpBodies),
+	}
+	if debug {
+		handler := slog.NewTextHandler(cmd.ErrOrStderr(), &slog.HandlerOptions{Level: slog.LevelDebug})
+		opts = append(opts, api.WithLogger(slog.New(handler)))
+	}
+	// An empty flag leaves UPBANK_API_URL or the default in place
+	if apiURL != "" {
+		opts = append(opts, api.WithBaseURL(apiURL))
+	}
+	// A token in the environment wins over one in the config file
+	if token, _ := api.TokenFromEnv(); token == "" {
+		if _, err := cfg.Decode("api-token", &token); err != nil {
+			return nil, err
+		}
+		if token != "" {
+			opts = append(opts, api.WithToken(token, "config file "+cfgPath))
+		}
+	}
+	return opts, nil
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	// Cancel the command context on Ctrl-C or SIGTERM so in-flight requests
+	// are aborted. A second signal falls back to the default behaviour.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	stop()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// exitCode maps an error returned by a command to a process exit code.
+func exitCode(err error) int {
+	if errors.Is(err, context.Canceled) {
+		return exitInterrupted
+	}
+
+	if errors.Is(err, api.ErrInvalidFilter) {
+		return exitValidation
+	}
+
+	var apiErr *api.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.IsAuthError():
+			return exitAuth
+		case apiErr.IsValidationError():
+			return exitValidation
+		case apiErr.IsRateLimited():
+			return exitRateLimited
+		case apiErr.IsServerError():
+			return exitServer
+		}
 	}
+	return exitError
+}
+
+func init() {
+	rootCmd.PersistentFlags().String("config", "", "Path to the JSON config file (default $UPBANK_CONFIG or <user config dir>/upbank-cli/config.json)")
+	rootCmd.PersistentFlags().String("api-url", "", "Base URL of the Up API, e.g. a local stand-in server (default $UPBANK_API_URL or "+api.DefaultBaseURL+")")
+	rootCmd.PersistentFlags().String("ca-bundle", "", "PEM file of additional CA certificates to trust, e.g. for a corporate proxy")
+	rootCmd.PersistentFlags().String("proxy", "", "Proxy URL for API requests (default from HTTP_PROXY/HTTPS_PROXY)")
+	rootCmd.PersistentFlags().String("record", "", "Record every API request and response to a cassette in this directory, with credentials and account numbers scrubbed")
+	rootCmd.PersistentFlags().String("replay", "", "Answer API requests from a cassette recorded with --record instead of calling Up")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay")
+	rootCmd.PersistentFlags().BoolP("debug", "v", false, "Log every API request (method, URL, page, status, latency and size) to stderr. The token is never logged")
+	rootCmd.PersistentFlags().String("dump-bodies", "", "Save the body of every API response to a numbered file in this directory, for debugging. Bodies are not scrubbed")
+	rootCmd.PersistentFlags().Duration("timeout", 0, "Overall deadline for the command (e.g. 2m). 0 disables the deadline")
+	rootCmd.PersistentFlags().Duration("request-timeout", api.DefaultRequestTimeout, "Deadline for each individual API request. 0 disables the deadline")
+	rootCmd.PersistentFlags().Int("retries", api.DefaultRetryPolicy.MaxRetries, "Maximum retries per request on rate limiting (429) or transient server errors. 0 disables retries")
+	rootCmd.PersistentFlags().Duration("retry-delay", api.DefaultRetryPolicy.BaseDelay, "Initial backoff between retries, doubled on each attempt with jitter")
+	rootCmd.PersistentFlags().Duration("retry-max-delay", api.DefaultRetryPolicy.MaxDelay, "Maximum backoff between retries (a server Retry-After is always honoured)")
+	rootCmd.PersistentFlags().Int("retry-budget", api.DefaultRetryPolicy.Budget, "Total retries allowed across the whole command. 0 means unlimited")
+	rootCmd.PersistentFlags().Float64("rate-limit", 0, "Maximum API requests per second, shared by concurrent requests such as those of --parallel. 0 means unlimited, except with --parallel, which defaults to 10")
 }
@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"upbank-cli/pkg/models"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// hasTag reports whether a transaction carries the tag.
+func hasTag(tx models.Transaction, tag string) bool {
+	for _, t := range tx.Relations.Tags.Data {
+		if t.ID == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// completeTags offers the tags in use for shell completion.
+func completeTags(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	client, err := newClient(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	tags, err := client.ListTags(cmd.Context())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var ids []string
+	for _, tag := range tags {
+		if strings.HasPrefix(tag.ID, toComplete) {
+			ids = append(ids, tag.ID)
+		}
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+// runTagChange adds or removes the tags given as arguments on a single
+// transaction or, in bulk mode, on every selected transaction.
+func runTagChange(cmd *cobra.Command, args []string, add bool) error {
+	bulk, err := bulkMode(cmd)
+	if err != nil {
+		return err
+	}
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	// A transaction ID unless in bulk mode, then at least one tag
+	want := 2
+	if bulk {
+		want--
+	}
+	if len(args) < want {
+		return fmt.Errorf("expected at least %d argument(s), got %d. See --help for usage", want, len(args))
+	}
+	tags := args
+	if !bulk {
+		tags = args[1:]
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	var transactions []models.Transaction
+	if bulk {
+		if transactions, err = bulkTransactions(cmd, client); err != nil {
+			return err
+		}
+	} else {
+		tx, err := client.GetTransaction(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		transactions = append(transactions, tx)
+	}
+
+	// Only send the tags that actually change on each transaction
+	verb, op := "Removed", "-"
+	if add {
+		verb, op = "Added", "+"
+	}
+	changes := make(map[string][]string)
+	var pending []models.Transaction
+	for _, tx := range transactions {
+		var changed []string
+		for _, tag := range tags {
+			if hasTag(tx, tag) != add {
+				changed = append(changed, tag)
+			}
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		changes[tx.ID] = changed
+		pending = append(pending, tx)
+	}
+	if unchanged := len(transactions) - len(pending); unchanged > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Skipping %d transactions that need no change\n", unchanged)
+	}
+	if len(pending) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Nothing to change")
+		return nil
+	}
+
+	if bulk || dryRun {
+		previewTransactions(cmd.OutOrStdout(), pending, func(tx models.Transaction) string {
+			return op + strings.Join(changes[tx.ID], " "+op)
+		})
+	}
+	if dryRun {
+		fmt.Fprintf(cmd.OutOrStdout(), "Dry run: %d transactions would be changed\n", len(pending))
+		return nil
+	}
+	if bulk {
+		ok, err := confirm(cmd, fmt.Sprintf("Change the tags of %d transactions?", len(pending)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(cmd.OutOrStdout(), "Aborted")
+			return nil
+		}
+	}
+
+	// Apply, carrying on past individual failures
+	update := client.RemoveTags
+	if add {
+		update = client.AddTags
+	}
+	var done, failed int
+	for _, tx := range pending {
+		if err := update(cmd.Context(), tx.ID, changes[tx.ID]...); err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("stopped after updating %d of %d transactions: %w", done, len(pending), err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Failed to update tags of %s: %v\n", tx.ID, err)
+			failed++
+			continue
+		}
+		done++
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "%s tags on %d of %d transactions\n", verb, done, len(pending))
+	if failed > 0 {
+		return fmt.Errorf("%d transactions could not be updated", failed)
+	}
+	return nil
+}
+
+var (
+	tagsCmd = &cobra.Command{
+		Use:   "tags",
+		Short: "List tags and tag transactions",
+		Long:  `List the tags in use, and add or remove tags on transactions.`,
+	}
+
+	tagsListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List all tags",
+		Long:  `List every tag currently in use, in the form accepted by --tag.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			rawMode, _ := cmd.Flags().GetBool("raw")
+			pageSize, _ := cmd.Flags().GetInt("page-size")
+
+			var tags []string
+			for tag, err := range client.Tags(cmd.Context(), pageSize) {
+				if err != nil {
+					return err
+				}
+				tags = append(tags, tag.ID)
+			}
+			slices.SortFunc(tags, func(a, b string) int {
+				return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+			})
+
+			t := table.NewWriter()
+			t.SetOutputMirror(cmd.OutOrStdout())
+			t.AppendHeader(table.Row{"Tag"})
+
+			// Use built-in dark style
+			if !rawMode {
+				t.SetStyle(table.StyleColoredRedWhiteOnBlack)
+			}
+
+			for _, tag := range tags {
+				t.AppendRow(table.Row{tag})
+			}
+			t.Render()
+			return nil
+		},
+	}
+
+	tagsAddCmd = &cobra.Command{
+		Use:   "add [<transaction-id>] <tag>...",
+		Short: "Add tags to transactions",
+		Long: `Add one or more tags to a transaction. Tags that don't exist yet are created.
+
+With --bulk the tags are added to every transaction matched by the transaction
+filter flags, and with --stdin to the transaction IDs read from stdin. Bulk
+changes are previewed and need confirmation unless --yes is given.`,
+		Example: `  upbank-cli tags add <transaction-id> work travel
+  upbank-cli tags add --bulk --since 2024-03-01 --until 2024-03-08 --currency JPY japan-trip`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTagChange(cmd, args, true)
+		},
+	}
+
+	tagsRemoveCmd = &cobra.Command{
+		Use:   "remove [<transaction-id>] <tag>...",
+		Short: "Remove tags from transactions",
+		Long: `Remove one or more tags from a transaction.
+
+With --bulk the tags are removed from every transaction matched by the
+transaction filter flags, and with --stdin from the transaction IDs read from
+stdin. Bulk changes are previewed and need confirmation unless --yes is given.`,
+		Example: `  upbank-cli tags remove <transaction-id> work
+  upbank-cli tags remove --bulk --tag travel --since 2024-01-01 travel`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTagChange(cmd, args, false)
+		},
+	}
+)
+
+func init() {
+	tagsListCmd.Flags().Bool("raw", false, "Display tags without pretty formatting")
+	tagsListCmd.Flags().Int("page-size", 0, "Number of tags to request per page (page[size]). Defaults to the Up API default")
+	addBulkFlags(tagsAddCmd)
+	addBulkFlags(tagsRemoveCmd)
+	tagsCmd.AddCommand(tagsListCmd, tagsAddCmd, tagsRemoveCmd)
+	rootCmd.AddCommand(tagsCmd)
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"upbank-cli/pkg/api"
+	"upbank-cli/pkg/models"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// runTagMerge moves every transaction tagged with one of sources over to
+// target. The target tag is added before the source tags are removed, so an
+// interrupted run never leaves a transaction without either tag, and running
+// it again only finds the transactions still carrying a source tag.
+func runTagMerge(cmd *cobra.Command, sources []string, target string) error {
+	// A tag given twice would be counted and removed twice
+	var unique []string
+	for _, source := range sources {
+		if !slices.Contains(unique, source) {
+			unique = append(unique, source)
+		}
+	}
+	sources = unique
+	if slices.Contains(sources, target) {
+		return fmt.Errorf("tag %q cannot be merged into itself", target)
+	}
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Collect every affected transaction before changing anything, since
+	// removing tags while paginating through filter[tag] would shift pages
+	var pending []models.Transaction
+	found := make(map[string][]string)
+	counts := make(map[string]int)
+	for _, source := range sources {
+		for tx, err := range client.Transactions(cmd.Context(), api.TransactionFilter{Tag: source}) {
+			if err != nil {
+				return err
+			}
+			counts[source]++
+			if _, seen := found[tx.ID]; !seen {
+				pending = append(pending, tx)
+			}
+			found[tx.ID] = append(found[tx.ID], source)
+		}
+	}
+
+	// Report what was found for each source tag
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.AppendHeader(table.Row{"Tag", "Transactions"})
+	for _, source := range sources {
+		t.AppendRow(table.Row{source, counts[source]})
+	}
+	t.Render()
+
+	if len(pending) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Nothing to change")
+		return nil
+	}
+
+	previewTransactions(cmd.OutOrStdout(), pending, func(tx models.Transaction) string {
+		change := "-" + strings.Join(found[tx.ID], " -")
+		if !hasTag(tx, target) {
+			change = "+" + target + " " + change
+		}
+		return change
+	})
+	if dryRun {
+		fmt.Fprintf(cmd.OutOrStdout(), "Dry run: %d transactions would be retagged as %s\n", len(pending), target)
+		return nil
+	}
+	ok, err := confirm(cmd, fmt.Sprintf("Retag %d transactions as %s?", len(pending), target))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintln(cmd.OutOrStdout(), "Aborted")
+		return nil
+	}
+
+	// Apply with progress, carrying on past individual failures
+	var done, failed int
+	for i, tx := range pending {
+		err := func() error {
+			if !hasTag(tx, target) {
+				if err := client.AddTags(cmd.Context(), tx.ID, target); err != nil {
+					return err
+				}
+			}
+			return client.RemoveTags(cmd.Context(), tx.ID, found[tx.ID]...)
+		}()
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("stopped after retagging %d of %d transactions, run the same command again to resume: %w", done, len(pending), err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "[%d/%d] Failed to retag %s: %v\n", i+1, len(pending), tx.ID, err)
+			failed++
+			continue
+		}
+		done++
+		fmt.Fprintf(cmd.ErrOrStderr(), "[%d/%d] Retagged %s\n", i+1, len(pending), tx.ID)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Retagged %d of %d transactions as %s\n", done, len(pending), target)
+	if failed > 0 {
+		return fmt.Errorf("%d transactions could not be retagged. Run the same command again to retry them", failed)
+	}
+	return nil
+}
+
+var (
+	tagsRenameCmd = &cobra.Command{
+		Use:   "rename <old> <new>",
+		Short: "Rename a tag on every transaction",
+		Long: `Rename a tag by adding the new tag to, and removing the old tag from, every
+transaction that carries it. Up has no endpoint to rename a tag directly.
+
+The new tag is added before the old one is removed, so an interrupted or
+partially failed rename can be resumed by running the same command again.`,
+		Example: `  upbank-cli tags rename Work work --dry-run`,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTagMerge(cmd, args[:1], args[1])
+		},
+	}
+
+	tagsMergeCmd = &cobra.Command{
+		Use:   "merge <tag>... --into <tag>",
+		Short: "Merge tags into a single tag",
+		Long: `Merge one or more tags into another by retagging every transaction that
+carries any of them with the --into tag and removing the merged tags.
+
+The --into tag is added before the merged tags are removed, so an interrupted
+or partially failed merge can be resumed by running the same command again.`,
+		Example: `  upbank-cli tags merge Work WORK --into work`,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			into, _ := cmd.Flags().GetString("into")
+			return runTagMerge(cmd, args, into)
+		},
+	}
+)
+
+func init() {
+	for _, c := range []*cobra.Command{tagsRenameCmd, tagsMergeCmd} {
+		c.Flags().Bool("dry-run", false, "Report the transactions that would be retagged without changing them")
+		c.Flags().BoolP("yes", "y", false, "Don't ask for confirmation before retagging")
+		c.ValidArgsFunction = completeTags
+	}
+	tagsMergeCmd.Flags().String("into", "", "Tag to merge into (required)")
+	_ = tagsMergeCmd.MarkFlagRequired("into")
+	_ = tagsMergeCmd.RegisterFlagCompletionFunc("into", completeTags)
+	tagsCmd.AddCommand(tagsRenameCmd, tagsMergeCmd)
+}
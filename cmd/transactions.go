@@ -1,19 +1,16 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"net/url"
-	"sort"
-	"strconv"
+	"iter"
 	"strings"
 	"time"
 	"upbank-cli/pkg/api"
 	"upbank-cli/pkg/models"
 
-	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
-	"golang.org/x/text/language"
-	"golang.org/x/text/message"
 )
 
 // parseDateTime parses a date string that can be either a date (YYYY-MM-DD) or datetime (RFC3339)
@@ -33,13 +30,135 @@ func parseDateTime(input string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("invalid date format. Use YYYY-MM-DD or RFC3339 format (e.g. 2020-01-01T01:02:03+10:00)")
 }
 
+// maxPageSize is the largest page[size] accepted by the Up API.
+const maxPageSize = api.MaxPageSize
+
+// clientFilter holds the transaction filters the Up API doesn't support,
+// applied after each page is fetched.
+type clientFilter struct {
+	currency    string
+	description string
+}
+
+// empty reports whether the filter lets every transaction through.
+func (f clientFilter) empty() bool {
+	return f.currency == "" && f.description == ""
+}
+
+// matches reports whether tx passes the filter.
+func (f clientFilter) matches(tx models.Transaction) bool {
+	// Filter by currency if specified
+	if f.currency != "" {
+		// Check if transaction has foreign amount with matching currency
+		if tx.Attributes.ForeignAmount == nil ||
+			!strings.EqualFold(tx.Attributes.ForeignAmount.CurrencyCode, f.currency) {
+			return false
+		}
+	}
+
+	// Filter by description if specified
+	if f.description != "" {
+		if !strings.Contains(strings.ToLower(tx.Attributes.Description), strings.ToLower(f.description)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// addTransactionFilterFlags registers the flags that select transactions on
+// every command operating on a set of transactions.
+func addTransactionFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().String("account", "", "Only include transactions of this account, given as an account ID or display name")
+	_ = cmd.RegisterFlagCompletionFunc("account", completeAccountNames)
+	cmd.Flags().String("status", "", "Filter transactions by status (HELD, SETTLED)")
+	cmd.Flags().String("since", "", "Filter transactions from this date/time (format: YYYY-MM-DD or RFC3339 e.g. 2020-01-01T01:02:03+10:00). For date-only input, time will be set to 00:00:00")
+	cmd.Flags().String("until", "", "Filter transactions until this date/time (format: YYYY-MM-DD or RFC3339 e.g. 2020-01-01T01:02:03+10:00). For date-only input, time will be set to 00:00:00")
+	cmd.Flags().String("category", "", "Filter transactions by category ID")
+	cmd.Flags().String("tag", "", "Filter transactions by tag ID")
+	_ = cmd.RegisterFlagCompletionFunc("tag", completeTags)
+	cmd.Flags().String("currency", "", "Filter transactions by foreign currency code (e.g., JPY). This is a client-side filter.")
+	cmd.Flags().String("description", "", "Filter transactions by description (case-insensitive partial match). This is a client-side filter.")
+}
+
+// transactionFiltersFromFlags builds the API and client-side filters from the
+// flags registered by addTransactionFilterFlags.
+func transactionFiltersFromFlags(cmd *cobra.Command) (api.TransactionFilter, clientFilter, error) {
+	status, _ := cmd.Flags().GetString("status")
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+	category, _ := cmd.Flags().GetString("category")
+	tag, _ := cmd.Flags().GetString("tag")
+	currency, _ := cmd.Flags().GetString("currency")
+	description, _ := cmd.Flags().GetString("description")
+
+	filter := api.TransactionFilter{
+		Status:   strings.ToUpper(status),
+		Category: category,
+		Tag:      tag,
+	}
+	if since != "" {
+		// Parse the since date
+		sinceTime, err := parseDateTime(since)
+		if err != nil {
+			return api.TransactionFilter{}, clientFilter{}, fmt.Errorf("invalid since date: %v", err)
+		}
+		filter.Since = sinceTime
+	}
+	if until != "" {
+		// Parse the until date
+		untilTime, err := parseDateTime(until)
+		if err != nil {
+			return api.TransactionFilter{}, clientFilter{}, fmt.Errorf("invalid until date: %v", err)
+		}
+		filter.Until = untilTime
+	}
+	if err := filter.Validate(); err != nil {
+		return api.TransactionFilter{}, clientFilter{}, err
+	}
+
+	return filter, clientFilter{currency: currency, description: description}, nil
+}
+
+// streamTransactions returns the transactions matching filter, limited to
+// the account given by the --account flag if set. With --parallel the date
+// range is fetched as several windows at once.
+func streamTransactions(cmd *cobra.Command, client api.UpClient, filter api.TransactionFilter) (iter.Seq2[models.Transaction, error], error) {
+	account, _ := cmd.Flags().GetString("account")
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	workers, _ := cmd.Flags().GetInt("parallel-workers")
+
+	source := client.Transactions
+	if account != "" {
+		resolved, err := resolveAccount(cmd.Context(), client, account)
+		if err != nil {
+			return nil, err
+		}
+		source = func(ctx context.Context, filter api.TransactionFilter) iter.Seq2[models.Transaction, error] {
+			return client.AccountTransactions(ctx, resolved.ID, filter)
+		}
+	}
+
+	if parallel > 1 {
+		if filter.Since.IsZero() {
+			return nil, fmt.Errorf("--parallel requires --since")
+		}
+		if workers < 1 {
+			return nil, fmt.Errorf("invalid parallel workers: must be at least 1")
+		}
+		return api.ParallelTransactions(cmd.Context(), source, filter, parallel, workers), nil
+	}
+	return source(cmd.Context(), filter), nil
+}
+
 var (
 	transactionsCmd = &cobra.Command{
 		Use:   "transactions",
 		Short: "List all transactions",
 		Long:  `List all transactions with their details. Supports filtering by status, date range, category, and tag.`,
+		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			client, err := api.NewClient()
+			client, err := newClient(cmd)
 			if err != nil {
 				return err
 			}
@@ -47,232 +166,90 @@ var (
 			// Get flag values
 			rawMode, _ := cmd.Flags().GetBool("raw")
 			detailMode, _ := cmd.Flags().GetBool("detail")
-			status, _ := cmd.Flags().GetString("status")
-			since, _ := cmd.Flags().GetString("since")
-			until, _ := cmd.Flags().GetString("until")
-			category, _ := cmd.Flags().GetString("category")
-			tag, _ := cmd.Flags().GetString("tag")
-			currency, _ := cmd.Flags().GetString("currency")
-			description, _ := cmd.Flags().GetString("description")
+			output, _ := cmd.Flags().GetString("output")
+			limit, _ := cmd.Flags().GetInt("limit")
+			pageSize, _ := cmd.Flags().GetInt("page-size")
 
-			// Build query parameters
-			params := make(map[string]string)
-			if status != "" {
-				params["filter[status]"] = status
-			}
-			if since != "" {
-				// Parse and format the since date
-				sinceTime, err := parseDateTime(since)
-				if err != nil {
-					return fmt.Errorf("invalid since date: %v", err)
-				}
-				params["filter[since]"] = sinceTime.Format(time.RFC3339)
-			}
-			if until != "" {
-				// Parse and format the until date
-				untilTime, err := parseDateTime(until)
-				if err != nil {
-					return fmt.Errorf("invalid until date: %v", err)
-				}
-				params["filter[until]"] = untilTime.Format(time.RFC3339)
+			filter, localFilter, err := transactionFiltersFromFlags(cmd)
+			if err != nil {
+				return err
 			}
-			if category != "" {
-				params["filter[category]"] = category
+
+			if limit < 0 {
+				return fmt.Errorf("invalid limit: must not be negative")
 			}
-			if tag != "" {
-				params["filter[tag]"] = tag
+			// Without client-side filters every row fetched is shown, so a
+			// small limit can be satisfied by a single page of that size
+			if pageSize == 0 && limit > 0 && limit <= maxPageSize && localFilter.empty() {
+				pageSize = limit
 			}
-
-			// URL encode the parameters
-			encodedParams := make(map[string]string)
-			for k, v := range params {
-				encodedParams[k] = url.QueryEscape(v)
+			filter.PageSize = pageSize
+			if err := filter.Validate(); err != nil {
+				return err
 			}
 
-			transactions, err := client.GetTransactions(encodedParams)
+			transactions, err := streamTransactions(cmd, client, filter)
 			if err != nil {
 				return err
 			}
 
-			// Apply client-side filters
-			var filteredTransactions []models.Transaction
-			for _, tx := range transactions {
-				// Filter by currency if specified
-				if currency != "" {
-					// Check if transaction has foreign amount with matching currency
-					if tx.Attributes.ForeignAmount == nil ||
-					   !strings.EqualFold(tx.Attributes.ForeignAmount.CurrencyCode, currency) {
-						continue
-					}
+			// Resolve category names for the pretty table
+			var categories categoryIndex
+			if output == "table" && !rawMode {
+				if categories, err = loadCategoryIndex(cmd, client, false); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: showing category IDs, could not load category names: %v\n", err)
 				}
-
-				// Filter by description if specified
-				if description != "" {
-					if !strings.Contains(strings.ToLower(tx.Attributes.Description), strings.ToLower(description)) {
-						continue
-					}
-				}
-
-				filteredTransactions = append(filteredTransactions, tx)
-			}
-
-			// Sort transactions by date (newest first)
-			sort.Sort(models.ByDate(filteredTransactions))
-
-			t := table.NewWriter()
-			t.SetOutputMirror(cmd.OutOrStdout())
-
-			// Set header based on mode
-			if rawMode {
-				t.AppendHeader(table.Row{"ID", "Date", "Description", "Message", "Amount", "Currency", "Foreign Amount", "Foreign Currency", "Status", "Category", "Tags"})
-			} else if detailMode {
-				t.AppendHeader(table.Row{"Date", "Description", "Message", "Amount", "Currency", "Foreign Amount", "Foreign Currency", "Category", "Tags"})
-			} else {
-				t.AppendHeader(table.Row{"Date", "Description", "Amount", "Currency", "Category"})
 			}
 
-			// Use built-in dark style
-			if !rawMode {
-				t.SetStyle(table.StyleColoredRedWhiteOnBlack)
+			w, err := newTransactionWriter(cmd.OutOrStdout(), output, rawMode, detailMode, categories)
+			if err != nil {
+				return err
 			}
 
-			// Create a new printer for number formatting
-			p := message.NewPrinter(language.English)
-
-			var totalDebit, totalCredit float64
-			for _, tx := range filteredTransactions {
-				amount, err := strconv.ParseFloat(tx.Attributes.Amount.Value, 64)
+			// Stream transactions into the writer page by page, newest first
+			var fetched, written int
+			for tx, err := range transactions {
 				if err != nil {
-					return fmt.Errorf("error parsing amount: %v", err)
-				}
-
-				// Track debit and credit totals
-				if amount < 0 {
-					totalDebit += amount
-				} else {
-					totalCredit += amount
-				}
-
-				// Format amount with thousand separator unless raw mode
-				formattedAmount := tx.Attributes.Amount.Value
-				if !rawMode {
-					// Convert to base units for proper formatting
-					baseUnits := tx.Attributes.Amount.ValueInBaseUnits
-					// Format with 2 decimal places
-					formattedAmount = p.Sprintf("%.2f", float64(baseUnits)/100.0)
-				}
-
-				// Format foreign amount if available and in detail mode
-				var formattedForeignAmount, foreignCurrency string
-				if (detailMode || rawMode) && tx.Attributes.ForeignAmount != nil {
-					formattedForeignAmount = tx.Attributes.ForeignAmount.Value
-					if !rawMode {
-						baseUnits := tx.Attributes.ForeignAmount.ValueInBaseUnits
-						formattedForeignAmount = p.Sprintf("%.2f", float64(baseUnits)/100.0)
+					// Flush what we have, then report how far pagination got
+					if closeErr := w.Close(); closeErr != nil {
+						return closeErr
 					}
-					foreignCurrency = tx.Attributes.ForeignAmount.CurrencyCode
-				}
-
-				// Format date
-				date := tx.Attributes.CreatedAt.Format(time.RFC3339)
-				if !rawMode {
-					date = tx.Attributes.CreatedAt.Format("Jan 02, 2006 15:04")
-				}
-
-				// Get category
-				categoryName := ""
-				if tx.Relations.Category.Data != nil {
-					categoryName = tx.Relations.Category.Data.ID
+					if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+						return fmt.Errorf("stopped after fetching %d transactions: %w", fetched, err)
+					}
+					return err
 				}
+				fetched++
 
-				// Get tags
-				var tags []string
-				for _, tag := range tx.Relations.Tags.Data {
-					tags = append(tags, tag.ID)
+				// Apply client-side filters
+				if !localFilter.matches(tx) {
+					continue
 				}
-				tagsStr := strings.Join(tags, ", ")
 
-				// Create row based on mode
-				if rawMode {
-					t.AppendRow(table.Row{
-						tx.ID,
-						date,
-						tx.Attributes.Description,
-						tx.Attributes.Message,
-						formattedAmount,
-						tx.Attributes.Amount.CurrencyCode,
-						formattedForeignAmount,
-						foreignCurrency,
-						tx.Attributes.Status,
-						categoryName,
-						tagsStr,
-					})
-				} else if detailMode {
-					t.AppendRow(table.Row{
-						date,
-						tx.Attributes.Description,
-						tx.Attributes.Message,
-						formattedAmount,
-						tx.Attributes.Amount.CurrencyCode,
-						formattedForeignAmount,
-						foreignCurrency,
-						categoryName,
-						tagsStr,
-					})
-				} else {
-					t.AppendRow(table.Row{
-						date,
-						tx.Attributes.Description,
-						formattedAmount,
-						tx.Attributes.Amount.CurrencyCode,
-						categoryName,
-					})
+				if err := w.Write(tx); err != nil {
+					return err
 				}
-			}
 
-			t.AppendSeparator()
-			// Format totals with thousand separator unless raw mode
-			if !rawMode {
-				formattedDebit := p.Sprintf("%.2f", totalDebit)
-				formattedCredit := p.Sprintf("%.2f", totalCredit)
-				if detailMode {
-					t.AppendFooter(table.Row{
-						"", "Debits 💸", "", formattedDebit, "AUD", "", "", "", "",
-					})
-					t.AppendFooter(table.Row{
-						"", "Credits 💰", "", formattedCredit, "AUD", "", "", "", "",
-					})
-					t.AppendFooter(table.Row{
-						"", "Net 🏦", "", p.Sprintf("%.2f", totalDebit+totalCredit), "AUD", "", "", "", "",
-					})
-				} else {
-					t.AppendFooter(table.Row{
-						"", "Debits 💸", formattedDebit, "AUD", "",
-					})
-					t.AppendFooter(table.Row{
-						"", "Credits 💰", formattedCredit, "AUD", "",
-					})
-					t.AppendFooter(table.Row{
-						"", "Net 🏦", p.Sprintf("%.2f", totalDebit+totalCredit), "AUD", "",
-					})
+				// Stop paginating once enough rows have been collected
+				written++
+				if limit > 0 && written >= limit {
+					break
 				}
 			}
 
-			t.Render()
-			return nil
+			return w.Close()
 		},
 	}
 )
 
 func init() {
 	transactionsCmd.Flags().Bool("raw", false, "Display raw numbers without pretty formatting")
+	transactionsCmd.Flags().StringP("output", "o", "table", "Output format: table, csv or json. csv and json are written as each page arrives")
+	transactionsCmd.Flags().Int("limit", 0, "Maximum number of transactions to display, counted after client-side filters. 0 means no limit")
+	transactionsCmd.Flags().Int("page-size", 0, "Number of transactions to request per page (page[size]). Defaults to the Up API default, or to --limit when it fits in one page")
 	transactionsCmd.Flags().Bool("detail", false, "Display detailed information including message, foreign amounts, and tags")
-	transactionsCmd.Flags().String("status", "", "Filter transactions by status (HELD, SETTLED)")
-	transactionsCmd.Flags().String("since", "", "Filter transactions from this date/time (format: YYYY-MM-DD or RFC3339 e.g. 2020-01-01T01:02:03+10:00). For date-only input, time will be set to 00:00:00")
-	transactionsCmd.Flags().String("until", "", "Filter transactions until this date/time (format: YYYY-MM-DD or RFC3339 e.g. 2020-01-01T01:02:03+10:00). For date-only input, time will be set to 00:00:00")
-	transactionsCmd.Flags().String("category", "", "Filter transactions by category ID")
-	transactionsCmd.Flags().String("tag", "", "Filter transactions by tag ID")
-	transactionsCmd.Flags().String("currency", "", "Filter transactions by foreign currency code (e.g., JPY). This is a client-side filter.")
-	transactionsCmd.Flags().String("description", "", "Filter transactions by description (case-insensitive partial match). This is a client-side filter.")
+	transactionsCmd.Flags().Int("parallel", 0, "Split the --since/--until range into this many date windows and fetch them concurrently, e.g. for years of history. Requires --since")
+	transactionsCmd.Flags().Int("parallel-workers", 4, "Maximum number of date windows fetched at once with --parallel")
+	addTransactionFilterFlags(transactionsCmd)
 	rootCmd.AddCommand(transactionsCmd)
 }
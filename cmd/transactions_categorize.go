@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"upbank-cli/pkg/models"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	transactionsCategorizeCmd = &cobra.Command{
+		Use:   "categorize [<transaction-id>] [<category>]",
+		Short: "Set or clear the category of transactions",
+		Long: `Set the category of a transaction, given as a category ID or name, or remove
+it with --clear. Only child categories can be assigned, and only to
+transactions Up allows to be categorized.
+
+Several transactions can be changed at once, either by piping their IDs on
+stdin with --stdin or by selecting them with the usual transaction filters and
+--bulk. Bulk changes are previewed and need confirmation unless --yes is given;
+--dry-run only shows the preview.`,
+		Example: `  upbank-cli transactions categorize <transaction-id> "Restaurants & Cafes"
+  upbank-cli transactions categorize <transaction-id> --clear
+  upbank-cli transactions categorize --bulk --description "Woolworths" groceries
+  upbank-cli transactions -o csv --description Uber | tail -n +2 | cut -d, -f1 | upbank-cli transactions categorize --stdin --yes taxis-and-share-cars`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bulk, err := bulkMode(cmd)
+			if err != nil {
+				return err
+			}
+			clearCategory, _ := cmd.Flags().GetBool("clear")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			// A transaction ID unless in bulk mode, then a category unless clearing
+			want := 2
+			if bulk {
+				want--
+			}
+			if clearCategory {
+				want--
+			}
+			if len(args) != want {
+				return fmt.Errorf("expected %d argument(s), got %d. See --help for usage", want, len(args))
+			}
+
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			// Resolve the target category
+			idx, err := loadCategoryIndex(cmd, client, false)
+			if err != nil && !clearCategory {
+				return err
+			}
+			var categoryID, categoryLabel string
+			if clearCategory {
+				categoryLabel = "(none)"
+			} else {
+				category, err := idx.resolve(args[len(args)-1])
+				if err != nil {
+					return err
+				}
+				categoryID, categoryLabel = category.ID, category.Attributes.Name
+			}
+
+			var transactions []models.Transaction
+			if bulk {
+				if transactions, err = bulkTransactions(cmd, client); err != nil {
+					return err
+				}
+			} else {
+				tx, err := client.GetTransaction(cmd.Context(), args[0])
+				if err != nil {
+					return err
+				}
+				transactions = append(transactions, tx)
+			}
+
+			// Skip what can't or needn't change
+			var pending []models.Transaction
+			var notCategorizable, unchanged int
+			for _, tx := range transactions {
+				if !tx.Attributes.IsCategorizable {
+					if !bulk {
+						return fmt.Errorf("transaction %s cannot be categorized", tx.ID)
+					}
+					notCategorizable++
+					continue
+				}
+				if transactionCategory(tx) == categoryID {
+					unchanged++
+					continue
+				}
+				pending = append(pending, tx)
+			}
+			if notCategorizable > 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Skipping %d transactions that cannot be categorized\n", notCategorizable)
+			}
+			if unchanged > 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Skipping %d transactions already in %s\n", unchanged, categoryLabel)
+			}
+			if len(pending) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "Nothing to change")
+				return nil
+			}
+
+			if bulk || dryRun {
+				previewTransactions(cmd.OutOrStdout(), pending, func(tx models.Transaction) string {
+					from := "(none)"
+					if id := transactionCategory(tx); id != "" {
+						from = idx.name(id)
+					}
+					return from + " → " + categoryLabel
+				})
+			}
+			if dryRun {
+				fmt.Fprintf(cmd.OutOrStdout(), "Dry run: %d transactions would be changed\n", len(pending))
+				return nil
+			}
+			if bulk {
+				ok, err := confirm(cmd, fmt.Sprintf("Change the category of %d transactions to %s?", len(pending), categoryLabel))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					fmt.Fprintln(cmd.OutOrStdout(), "Aborted")
+					return nil
+				}
+			}
+
+			// Apply, carrying on past individual failures
+			var changed, failed int
+			for _, tx := range pending {
+				if err := client.Categorize(cmd.Context(), tx.ID, categoryID); err != nil {
+					if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+						return fmt.Errorf("stopped after categorizing %d of %d transactions: %w", changed, len(pending), err)
+					}
+					fmt.Fprintf(cmd.ErrOrStderr(), "Failed to categorize %s: %v\n", tx.ID, err)
+					failed++
+					continue
+				}
+				changed++
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Categorized %d of %d transactions as %s\n", changed, len(pending), categoryLabel)
+			if failed > 0 {
+				return fmt.Errorf("%d transactions could not be categorized", failed)
+			}
+			return nil
+		},
+	}
+)
+
+func init() {
+	transactionsCategorizeCmd.Flags().Bool("clear", false, "Remove the category instead of setting one")
+	addBulkFlags(transactionsCategorizeCmd)
+	transactionsCmd.AddCommand(transactionsCategorizeCmd)
+}
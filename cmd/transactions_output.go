@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"upbank-cli/pkg/models"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// transactionWriter renders transactions one at a time as they are streamed
+// from the API. Close must be called once all transactions have been
+// written, including after an error, to flush any buffered output.
+type transactionWriter interface {
+	Write(tx models.Transaction) error
+	Close() error
+}
+
+// newTransactionWriter returns the writer for the given output format. The
+// table writer shows category names from categories when it is not nil.
+func newTransactionWriter(w io.Writer, format string, rawMode, detailMode bool, categories categoryIndex) (transactionWriter, error) {
+	switch format {
+	case "table":
+		return newTableTransactionWriter(w, rawMode, detailMode, categories), nil
+	case "csv":
+		return newCSVTransactionWriter(w)
+	case "json":
+		return &jsonTransactionWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("invalid output format %q. Use table, csv or json", format)
+	}
+}
+
+// transactionCategory returns the category ID of a transaction, if any.
+func transactionCategory(tx models.Transaction) string {
+	if tx.Relations.Category.Data != nil {
+		return tx.Relations.Category.Data.ID
+	}
+	return ""
+}
+
+// transactionParentCategory returns the parent category ID of a
+// transaction, if any.
+func transactionParentCategory(tx models.Transaction) string {
+	if tx.Relations.ParentCategory.Data != nil {
+		return tx.Relations.ParentCategory.Data.ID
+	}
+	return ""
+}
+
+// transactionTags returns the tag IDs of a transaction joined by commas.
+func transactionTags(tx models.Transaction) string {
+	var tags []string
+	for _, tag := range tx.Relations.Tags.Data {
+		tags = append(tags, tag.ID)
+	}
+	return strings.Join(tags, ", ")
+}
+
+// tableTransactionWriter renders transactions as a table with debit, credit
+// and net totals. Rows are formatted as they arrive; the table itself is
+// printed on Close since column widths depend on every row.
+type tableTransactionWriter struct {
+	t           table.Writer
+	p           *message.Printer
+	rawMode     bool
+	detailMode  bool
+	categories  categoryIndex
+	totalDebit  float64
+	totalCredit float64
+}
+
+func newTableTransactionWriter(w io.Writer, rawMode, detailMode bool, categories categoryIndex) *tableTransactionWriter {
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+
+	// Set header based on mode
+	if rawMode {
+		t.AppendHeader(table.Row{"ID", "Date", "Description", "Message", "Amount", "Currency", "Foreign Amount", "Foreign Currency", "Status", "Category", "Tags"})
+	} else if detailMode {
+		t.AppendHeader(table.Row{"Date", "Description", "Message", "Amount", "Currency", "Foreign Amount", "Foreign Currency", "Category", "Parent Category", "Tags"})
+	} else {
+		t.AppendHeader(table.Row{"Date", "Description", "Amount", "Currency", "Category"})
+	}
+
+	// Use built-in dark style
+	if !rawMode {
+		t.SetStyle(table.StyleColoredRedWhiteOnBlack)
+	}
+
+	return &tableTransactionWriter{
+		t: t,
+		// Create a new printer for number formatting
+		p:          message.NewPrinter(language.English),
+		rawMode:    rawMode,
+		detailMode: detailMode,
+		categories: categories,
+	}
+}
+
+func (w *tableTransactionWriter) Write(tx models.Transaction) error {
+	amount, err := strconv.ParseFloat(tx.Attributes.Amount.Value, 64)
+	if err != nil {
+		return fmt.Errorf("error parsing amount: %v", err)
+	}
+
+	// Track debit and credit totals
+	if amount < 0 {
+		w.totalDebit += amount
+	} else {
+		w.totalCredit += amount
+	}
+
+	// Format amount with thousand separator unless raw mode
+	formattedAmount := tx.Attributes.Amount.Value
+	if !w.rawMode {
+		// Convert to base units for proper formatting
+		baseUnits := tx.Attributes.Amount.ValueInBaseUnits
+		// Format with 2 decimal places
+		formattedAmount = w.p.Sprintf("%.2f", float64(baseUnits)/100.0)
+	}
+
+	// Format foreign amount if available and in detail mode
+	var formattedForeignAmount, foreignCurrency string
+	if (w.detailMode || w.rawMode) && tx.Attributes.ForeignAmount != nil {
+		formattedForeignAmount = tx.Attributes.ForeignAmount.Value
+		if !w.rawMode {
+			baseUnits := tx.Attributes.ForeignAmount.ValueInBaseUnits
+			formattedForeignAmount = w.p.Sprintf("%.2f", float64(baseUnits)/100.0)
+		}
+		foreignCurrency = tx.Attributes.ForeignAmount.CurrencyCode
+	}
+
+	// Format date
+	date := tx.Attributes.CreatedAt.Format(time.RFC3339)
+	if !w.rawMode {
+		date = tx.Attributes.CreatedAt.Format("Jan 02, 2006 15:04")
+	}
+
+	// Show category names unless raw mode
+	categoryName := transactionCategory(tx)
+	parentCategoryName := transactionParentCategory(tx)
+	if !w.rawMode && w.categories != nil {
+		if categoryName != "" {
+			categoryName = w.categories.name(categoryName)
+		}
+		if parentCategoryName != "" {
+			parentCategoryName = w.categories.name(parentCategoryName)
+		}
+	}
+	tagsStr := transactionTags(tx)
+
+	// Create row based on mode
+	if w.rawMode {
+		w.t.AppendRow(table.Row{
+			tx.ID,
+			date,
+			tx.Attributes.Description,
+			tx.Attributes.Message,
+			formattedAmount,
+			tx.Attributes.Amount.CurrencyCode,
+			formattedForeignAmount,
+			foreignCurrency,
+			tx.Attributes.Status,
+			categoryName,
+			tagsStr,
+		})
+	} else if w.detailMode {
+		w.t.AppendRow(table.Row{
+			date,
+			tx.Attributes.Description,
+			tx.Attributes.Message,
+			formattedAmount,
+			tx.Attributes.Amount.CurrencyCode,
+			formattedForeignAmount,
+			foreignCurrency,
+			categoryName,
+			parentCategoryName,
+			tagsStr,
+		})
+	} else {
+		w.t.AppendRow(table.Row{
+			date,
+			tx.Attributes.Description,
+			formattedAmount,
+			tx.Attributes.Amount.CurrencyCode,
+			categoryName,
+		})
+	}
+	return nil
+}
+
+func (w *tableTransactionWriter) Close() error {
+	p := w.p
+	totalDebit, totalCredit := w.totalDebit, w.totalCredit
+
+	w.t.AppendSeparator()
+	// Format totals with thousand separator unless raw mode
+	if !w.rawMode {
+		formattedDebit := p.Sprintf("%.2f", totalDebit)
+		formattedCredit := p.Sprintf("%.2f", totalCredit)
+		if w.detailMode {
+			w.t.AppendFooter(table.Row{
+				"", "Debits 💸", "", formattedDebit, "AUD", "", "", "", "", "",
+			})
+			w.t.AppendFooter(table.Row{
+				"", "Credits 💰", "", formattedCredit, "AUD", "", "", "", "", "",
+			})
+			w.t.AppendFooter(table.Row{
+				"", "Net 🏦", "", p.Sprintf("%.2f", totalDebit+totalCredit), "AUD", "", "", "", "", "",
+			})
+		} else {
+			w.t.AppendFooter(table.Row{
+				"", "Debits 💸", formattedDebit, "AUD", "",
+			})
+			w.t.AppendFooter(table.Row{
+				"", "Credits 💰", formattedCredit, "AUD", "",
+			})
+			w.t.AppendFooter(table.Row{
+				"", "Net 🏦", p.Sprintf("%.2f", totalDebit+totalCredit), "AUD", "",
+			})
+		}
+	}
+
+	w.t.Render()
+	return nil
+}
+
+// csvTransactionWriter writes one CSV record per transaction, flushing each
+// record as soon as it is written.
+type csvTransactionWriter struct {
+	w *csv.Writer
+}
+
+func newCSVTransactionWriter(w io.Writer) (*csvTransactionWriter, error) {
+	cw := csv.NewWriter(w)
+	header := []string{"ID", "Date", "Description", "Message", "Amount", "Currency", "Foreign Amount", "Foreign Currency", "Status", "Category", "Tags"}
+	if err := cw.Write(header); err != nil {
+		return nil, err
+	}
+	cw.Flush()
+	return &csvTransactionWriter{w: cw}, cw.Error()
+}
+
+func (w *csvTransactionWriter) Write(tx models.Transaction) error {
+	var foreignAmount, foreignCurrency string
+	if tx.Attributes.ForeignAmount != nil {
+		foreignAmount = tx.Attributes.ForeignAmount.Value
+		foreignCurrency = tx.Attributes.ForeignAmount.CurrencyCode
+	}
+
+	if err := w.w.Write([]string{
+		tx.ID,
+		tx.Attributes.CreatedAt.Format(time.RFC3339),
+		tx.Attributes.Description,
+		tx.Attributes.Message,
+		tx.Attributes.Amount.Value,
+		tx.Attributes.Amount.CurrencyCode,
+		foreignAmount,
+		foreignCurrency,
+		tx.Attributes.Status,
+		transactionCategory(tx),
+		transactionTags(tx),
+	}); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func (w *csvTransactionWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// jsonTransactionWriter writes transactions as a JSON array, emitting each
+// element as soon as it is written.
+type jsonTransactionWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (w *jsonTransactionWriter) Write(tx models.Transaction) error {
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n  "
+	if w.count == 0 {
+		sep = "[\n  "
+	}
+	w.count++
+	_, err = fmt.Fprintf(w.w, "%s%s", sep, data)
+	return err
+}
+
+func (w *jsonTransactionWriter) Close() error {
+	if w.count == 0 {
+		_, err := fmt.Fprintln(w.w, "[]")
+		return err
+	}
+	_, err := fmt.Fprintln(w.w, "\n]")
+	return err
+}
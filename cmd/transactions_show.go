@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	transactionsShowCmd = &cobra.Command{
+		Use:   "show <transaction-id>",
+		Short: "Show a single transaction",
+		Long: `Show every detail of a single transaction, retrieved by its ID, including
+hold information, round-up, cashback, card purchase method, note, performing
+customer and deep link.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			rawMode, _ := cmd.Flags().GetBool("raw")
+
+			tx, err := client.GetTransaction(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			// Category names are shown alongside IDs unless raw mode
+			var categories categoryIndex
+			if !rawMode {
+				if categories, err = loadCategoryIndex(cmd, client, false); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: showing category IDs, could not load category names: %v\n", err)
+				}
+			}
+			categoryField := func(id string) string {
+				if id == "" || categories == nil {
+					return id
+				}
+				return fmt.Sprintf("%s (%s)", categories.name(id), id)
+			}
+
+			v := newDetailView(cmd.OutOrStdout(), rawMode)
+			attr := tx.Attributes
+			rel := tx.Relations
+
+			v.Field("ID", tx.ID)
+			v.Field("Status", attr.Status)
+			v.Field("Description", attr.Description)
+			v.Field("Message", attr.Message)
+			if attr.RawText != nil {
+				v.Field("Raw Text", *attr.RawText)
+			}
+			if attr.TransactionType != nil {
+				v.Field("Transaction Type", *attr.TransactionType)
+			}
+			v.Field("Amount", v.Money(&attr.Amount))
+			v.Field("Foreign Amount", v.Money(attr.ForeignAmount))
+
+			// Hold info is only present while a transaction is held, or if
+			// the settled amount differs from the held amount
+			if attr.HoldInfo != nil {
+				v.Field("Hold Amount", v.Money(&attr.HoldInfo.Amount))
+				v.Field("Hold Foreign Amount", v.Money(attr.HoldInfo.ForeignAmount))
+			}
+			if attr.RoundUp != nil {
+				v.Field("Round Up", v.Money(&attr.RoundUp.Amount))
+				v.Field("Round Up Boost", v.Money(attr.RoundUp.BoostPortion))
+			}
+			if attr.Cashback != nil {
+				v.Field("Cashback", v.Money(&attr.Cashback.Amount))
+				v.Field("Cashback Description", attr.Cashback.Description)
+			}
+			if attr.CardPurchaseMethod != nil {
+				v.Field("Card Purchase Method", attr.CardPurchaseMethod.Method)
+				if attr.CardPurchaseMethod.CardNumberSuffix != nil {
+					v.Field("Card Number Suffix", *attr.CardPurchaseMethod.CardNumberSuffix)
+				}
+			}
+			if attr.Note != nil {
+				v.Field("Note", attr.Note.Text)
+			}
+			v.Field("Performing Customer", attr.PerformingCustomer.DisplayName)
+			v.Field("Categorizable", strconv.FormatBool(attr.IsCategorizable))
+			v.Field("Created At", v.Time(attr.CreatedAt))
+			v.Field("Settled At", v.Time(attr.SettledAt))
+
+			// Related resources
+			v.Field("Account", rel.Account.Data.ID)
+			if rel.TransferAccount.Data != nil {
+				v.Field("Transfer Account", rel.TransferAccount.Data.ID)
+			}
+			v.Field("Category", categoryField(transactionCategory(tx)))
+			v.Field("Parent Category", categoryField(transactionParentCategory(tx)))
+			v.Field("Tags", transactionTags(tx))
+			if rel.Attachment.Data != nil {
+				v.Field("Attachment", rel.Attachment.Data.ID)
+			}
+			v.Field("Deep Link", attr.DeepLinkURL)
+
+			v.Render()
+			return nil
+		},
+	}
+)
+
+func init() {
+	transactionsShowCmd.Flags().Bool("raw", false, "Display raw values without pretty formatting")
+	transactionsCmd.AddCommand(transactionsShowCmd)
+}
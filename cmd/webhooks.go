@@ -0,0 +1,326 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+	"upbank-cli/pkg/api"
+	"upbank-cli/pkg/models"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// defaultWebhookHTTPClient gives up on a receiver that doesn't answer within
+// the same deadline as a request to the Up API.
+var defaultWebhookHTTPClient = &http.Client{Timeout: api.DefaultRequestTimeout}
+
+// webhookHTTPClient delivers the events of "webhooks send" and "webhooks
+// replay" to a local receiver.
+var webhookHTTPClient = defaultWebhookHTTPClient
+
+// SetWebhookHTTPClient replaces the HTTP client used to deliver events to a
+// local receiver, e.g. to change the timeout or deliver to an in-process
+// handler. A nil client restores the default, which times out after
+// api.DefaultRequestTimeout.
+func SetWebhookHTTPClient(c *http.Client) {
+	if c == nil {
+		c = defaultWebhookHTTPClient
+	}
+	webhookHTTPClient = c
+}
+
+// webhookDescription returns the description of a webhook, if any.
+func webhookDescription(w models.Webhook) string {
+	if w.Attributes.Description != nil {
+		return *w.Attributes.Description
+	}
+	return ""
+}
+
+// writeSecretFile saves a webhook secret key so that only the current user
+// can read it.
+func writeSecretFile(path, secret string) error {
+	if err := os.WriteFile(path, []byte(secret+"\n"), 0o600); err != nil {
+		return fmt.Errorf("error saving secret key: %v", err)
+	}
+	return nil
+}
+
+// readSecretFile reads a webhook secret key saved by writeSecretFile.
+func readSecretFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading secret key: %v", err)
+	}
+	secret := bytes.TrimSpace(data)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("secret key file %s is empty", path)
+	}
+	return secret, nil
+}
+
+// truncate shortens s to at most n runes for display in a table.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+var (
+	webhooksCmd = &cobra.Command{
+		Use:   "webhooks",
+		Short: "Manage webhooks",
+		Long:  `Create, list, inspect, ping and delete the webhooks Up delivers transaction events to.`,
+	}
+
+	webhooksCreateCmd = &cobra.Command{
+		Use:   "create",
+		Short: "Create a webhook",
+		Long: `Register a URL to receive transaction events from Up.
+
+The secret key used to sign deliveries is printed once. Up never returns it
+again, so store it safely, e.g. with --secret-file.`,
+		Example: `  upbank-cli webhooks create --url https://example.com/up --description "Budget sync" --secret-file up-secret`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			webhookURL, _ := cmd.Flags().GetString("url")
+			description, _ := cmd.Flags().GetString("description")
+			secretFile, _ := cmd.Flags().GetString("secret-file")
+
+			webhook, err := client.CreateWebhook(cmd.Context(), webhookURL, description)
+			if err != nil {
+				return err
+			}
+
+			var secret string
+			if webhook.Attributes.SecretKey != nil {
+				secret = *webhook.Attributes.SecretKey
+			}
+
+			v := newDetailView(cmd.OutOrStdout(), false)
+			v.Field("ID", webhook.ID)
+			v.Field("URL", webhook.Attributes.URL)
+			v.Field("Description", webhookDescription(webhook))
+			v.Field("Created At", v.Time(webhook.Attributes.CreatedAt))
+			v.Field("Secret Key", secret)
+			v.Render()
+
+			if secretFile != "" {
+				if err := writeSecretFile(secretFile, secret); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "Secret key saved to %s\n", secretFile)
+			} else {
+				fmt.Fprintln(cmd.ErrOrStderr(), "Store the secret key now, it will not be shown again")
+			}
+			return nil
+		},
+	}
+
+	webhooksListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List all webhooks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			rawMode, _ := cmd.Flags().GetBool("raw")
+			pageSize, _ := cmd.Flags().GetInt("page-size")
+
+			t := table.NewWriter()
+			t.SetOutputMirror(cmd.OutOrStdout())
+			t.AppendHeader(table.Row{"ID", "URL", "Description", "Created At"})
+
+			// Use built-in dark style
+			if !rawMode {
+				t.SetStyle(table.StyleColoredRedWhiteOnBlack)
+			}
+
+			for webhook, err := range client.Webhooks(cmd.Context(), pageSize) {
+				if err != nil {
+					return err
+				}
+
+				createdAt := webhook.Attributes.CreatedAt.Format(time.RFC3339)
+				if !rawMode {
+					createdAt = webhook.Attributes.CreatedAt.Format("Jan 02, 2006 15:04")
+				}
+				t.AppendRow(table.Row{webhook.ID, webhook.Attributes.URL, webhookDescription(webhook), createdAt})
+			}
+
+			t.Render()
+			return nil
+		},
+	}
+
+	webhooksShowCmd = &cobra.Command{
+		Use:   "show <webhook-id>",
+		Short: "Show a single webhook",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			rawMode, _ := cmd.Flags().GetBool("raw")
+
+			webhook, err := client.GetWebhook(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			v := newDetailView(cmd.OutOrStdout(), rawMode)
+			v.Field("ID", webhook.ID)
+			v.Field("URL", webhook.Attributes.URL)
+			v.Field("Description", webhookDescription(webhook))
+			v.Field("Created At", v.Time(webhook.Attributes.CreatedAt))
+			v.Render()
+			return nil
+		},
+	}
+
+	webhooksDeleteCmd = &cobra.Command{
+		Use:   "delete <webhook-id>",
+		Short: "Delete a webhook",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			ok, err := confirm(cmd, fmt.Sprintf("Delete webhook %s? It will stop receiving events.", args[0]))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Fprintln(cmd.OutOrStdout(), "Aborted")
+				return nil
+			}
+
+			if err := client.DeleteWebhook(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Deleted webhook %s\n", args[0])
+			return nil
+		},
+	}
+
+	webhooksPingCmd = &cobra.Command{
+		Use:   "ping <webhook-id>",
+		Short: "Send a PING event to a webhook",
+		Long:  `Ask Up to deliver a PING event to a webhook. Use "webhooks logs" to see how the endpoint responded.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			event, err := client.PingWebhook(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			v := newDetailView(cmd.OutOrStdout(), false)
+			v.Field("Event ID", event.ID)
+			v.Field("Event Type", event.Attributes.EventType)
+			v.Field("Created At", v.Time(event.Attributes.CreatedAt))
+			v.Render()
+			return nil
+		},
+	}
+
+	webhooksLogsCmd = &cobra.Command{
+		Use:   "logs <webhook-id>",
+		Short: "Show the delivery logs of a webhook",
+		Long: `Show the attempts Up made to deliver events to a webhook, newest first,
+with the response status and body returned by the endpoint.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			rawMode, _ := cmd.Flags().GetBool("raw")
+			limit, _ := cmd.Flags().GetInt("limit")
+			pageSize, _ := cmd.Flags().GetInt("page-size")
+
+			t := table.NewWriter()
+			t.SetOutputMirror(cmd.OutOrStdout())
+			t.AppendHeader(table.Row{"Created At", "Event ID", "Delivery Status", "Response Status", "Response Body"})
+
+			// Use built-in dark style
+			if !rawMode {
+				t.SetStyle(table.StyleColoredRedWhiteOnBlack)
+			}
+
+			var count int
+			for log, err := range client.WebhookLogs(cmd.Context(), args[0], pageSize) {
+				if err != nil {
+					return err
+				}
+
+				attr := log.Attributes
+				createdAt := attr.CreatedAt.Format(time.RFC3339)
+				if !rawMode {
+					createdAt = attr.CreatedAt.Format("Jan 02, 2006 15:04")
+				}
+				var status, body string
+				if attr.Response != nil {
+					status = strconv.Itoa(attr.Response.StatusCode)
+					body = attr.Response.Body
+					if !rawMode {
+						body = truncate(body, 60)
+					}
+				}
+				t.AppendRow(table.Row{createdAt, log.Relations.WebhookEvent.Data.ID, attr.DeliveryStatus, status, body})
+
+				count++
+				if limit > 0 && count >= limit {
+					break
+				}
+			}
+
+			t.Render()
+			return nil
+		},
+	}
+)
+
+func init() {
+	webhooksCreateCmd.Flags().String("url", "", "URL to deliver events to (required)")
+	webhooksCreateCmd.Flags().String("description", "", "Description of the webhook")
+	webhooksCreateCmd.Flags().String("secret-file", "", "Also save the secret key to this file, readable only by you")
+	_ = webhooksCreateCmd.MarkFlagRequired("url")
+
+	webhooksListCmd.Flags().Bool("raw", false, "Display raw values without pretty formatting")
+	webhooksListCmd.Flags().Int("page-size", 0, "Number of webhooks to request per page (page[size]). Defaults to the Up API default")
+
+	webhooksShowCmd.Flags().Bool("raw", false, "Display raw values without pretty formatting")
+
+	webhooksDeleteCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation before deleting")
+
+	webhooksLogsCmd.Flags().Bool("raw", false, "Display raw values and full response bodies without pretty formatting")
+	webhooksLogsCmd.Flags().Int("limit", 0, "Maximum number of logs to display. 0 means no limit")
+	webhooksLogsCmd.Flags().Int("page-size", 0, "Number of logs to request per page (page[size]). Defaults to the Up API default")
+
+	webhooksCmd.AddCommand(webhooksCreateCmd, webhooksListCmd, webhooksShowCmd, webhooksDeleteCmd, webhooksPingCmd, webhooksLogsCmd)
+	rootCmd.AddCommand(webhooksCmd)
+}
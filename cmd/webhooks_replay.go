@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+	"upbank-cli/pkg/models"
+	"upbank-cli/pkg/webhook"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// defaultLedgerPath returns where the delivery ledger shared by "webhooks
+// serve" and "webhooks replay" is kept.
+func defaultLedgerPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "webhook-ledger.jsonl"
+	}
+	return filepath.Join(dir, "upbank-cli", "webhook-ledger.jsonl")
+}
+
+// replayEvent is an event reconstructed from a webhook's delivery logs.
+type replayEvent struct {
+	id        string
+	eventType string
+	createdAt time.Time
+	body      []byte
+}
+
+// replayEvents collects the distinct events delivered to a webhook between
+// since and until, oldest first. Up logs every delivery attempt, so retried
+// events appear more than once.
+func replayEvents(cmd *cobra.Command, webhookID string, since, until time.Time) ([]replayEvent, error) {
+	client, err := newClient(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var events []replayEvent
+	for log, err := range client.WebhookLogs(cmd.Context(), webhookID, maxPageSize) {
+		if err != nil {
+			return nil, err
+		}
+
+		// Logs are newest first, so everything after this is too old
+		createdAt := log.Attributes.CreatedAt
+		if createdAt.Before(since) {
+			break
+		}
+		if !until.IsZero() && createdAt.After(until) {
+			continue
+		}
+
+		id := log.Relations.WebhookEvent.Data.ID
+		body := log.Attributes.Request.Body
+		if id == "" || body == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		event := replayEvent{id: id, createdAt: createdAt, body: []byte(body)}
+		var payload models.WebhookEventResponse
+		if err := json.Unmarshal(event.body, &payload); err == nil {
+			event.eventType = payload.Data.Attributes.EventType
+			event.createdAt = payload.Data.Attributes.CreatedAt
+		}
+		events = append(events, event)
+	}
+
+	slices.Reverse(events)
+	return events, nil
+}
+
+var webhooksReplayCmd = &cobra.Command{
+	Use:   "replay <webhook-id>",
+	Short: "Re-deliver logged webhook events to a local receiver",
+	Long: `Re-deliver the events Up sent to a webhook, for example after a local
+receiver was down.
+
+The original request bodies are taken from the webhook's delivery logs,
+re-signed with the secret key and sent to --to, oldest first. Events already
+acknowledged according to the local delivery ledger are skipped. "webhooks
+serve" records every event it acknowledges in the same ledger, and replayed
+events are recorded once delivered, so running replay again only sends what
+is still missing.`,
+	Example: `  upbank-cli webhooks replay <webhook-id> --since 2024-03-01 --to http://localhost:8080 --secret-file up-secret
+  upbank-cli webhooks replay <webhook-id> --since 2024-03-01T09:00:00+11:00 --to http://localhost:8080 --secret-file up-secret --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sinceStr, _ := cmd.Flags().GetString("since")
+		untilStr, _ := cmd.Flags().GetString("until")
+		to, _ := cmd.Flags().GetString("to")
+		secretFile, _ := cmd.Flags().GetString("secret-file")
+		ledgerPath, _ := cmd.Flags().GetString("ledger")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		since, err := parseDateTime(sinceStr)
+		if err != nil {
+			return fmt.Errorf("invalid since date: %v", err)
+		}
+		var until time.Time
+		if untilStr != "" {
+			if until, err = parseDateTime(untilStr); err != nil {
+				return fmt.Errorf("invalid until date: %v", err)
+			}
+		}
+
+		secret, err := readSecretFile(secretFile)
+		if err != nil {
+			return err
+		}
+		ledger, err := webhook.OpenLedger(ledgerPath)
+		if err != nil {
+			return err
+		}
+
+		events, err := replayEvents(cmd, args[0], since, until)
+		if err != nil {
+			return err
+		}
+		var pending []replayEvent
+		for _, event := range events {
+			if !ledger.Has(event.id) {
+				pending = append(pending, event)
+			}
+		}
+		if acked := len(events) - len(pending); acked > 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Skipping %d events already acknowledged\n", acked)
+		}
+		if len(pending) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "Nothing to replay")
+			return nil
+		}
+
+		if dryRun {
+			t := table.NewWriter()
+			t.SetOutputMirror(cmd.OutOrStdout())
+			t.AppendHeader(table.Row{"Event ID", "Event Type", "Created At"})
+			for _, event := range pending {
+				t.AppendRow(table.Row{event.id, event.eventType, event.createdAt.Format("Jan 02, 2006 15:04")})
+			}
+			t.Render()
+			fmt.Fprintf(cmd.OutOrStdout(), "Dry run: %d events would be replayed to %s\n", len(pending), to)
+			return nil
+		}
+
+		// Deliver in order, carrying on past individual failures
+		var done, failed int
+		for i, event := range pending {
+			if err := webhook.Deliver(cmd.Context(), webhookHTTPClient, to, secret, event.body); err != nil {
+				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+					return fmt.Errorf("stopped after replaying %d of %d events: %w", done, len(pending), err)
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "[%d/%d] Failed to replay event %s: %v\n", i+1, len(pending), event.id, err)
+				failed++
+				continue
+			}
+			if err := ledger.Record(webhook.LedgerEntry{
+				EventID:      event.id,
+				WebhookID:    args[0],
+				EventType:    event.eventType,
+				Acknowledged: time.Now(),
+			}); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %v\n", err)
+			}
+			done++
+			fmt.Fprintf(cmd.OutOrStdout(), "[%d/%d] Replayed %s event %s\n", i+1, len(pending), event.eventType, event.id)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Replayed %d of %d events\n", done, len(pending))
+		if failed > 0 {
+			return fmt.Errorf("%d events could not be replayed. Run the same command again to retry them", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	webhooksReplayCmd.Flags().String("since", "", "Replay events logged at or after this date (YYYY-MM-DD or RFC3339) (required)")
+	webhooksReplayCmd.Flags().String("until", "", "Replay events logged at or before this date (YYYY-MM-DD or RFC3339)")
+	webhooksReplayCmd.Flags().String("to", "", "URL of the receiver to deliver to (required)")
+	webhooksReplayCmd.Flags().String("secret-file", "", "File containing the secret key to sign events with (required)")
+	webhooksReplayCmd.Flags().String("ledger", defaultLedgerPath(), "Path to the delivery ledger of acknowledged events")
+	webhooksReplayCmd.Flags().Bool("dry-run", false, "List the events that would be replayed without sending them")
+	_ = webhooksReplayCmd.MarkFlagRequired("since")
+	_ = webhooksReplayCmd.MarkFlagRequired("to")
+	_ = webhooksReplayCmd.MarkFlagRequired("secret-file")
+
+	webhooksCmd.AddCommand(webhooksReplayCmd)
+}
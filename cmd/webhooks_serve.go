@@ -0,0 +1,340 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"upbank-cli/pkg/api"
+	"upbank-cli/pkg/hooks"
+	"upbank-cli/pkg/models"
+	"upbank-cli/pkg/webhook"
+
+	"github.com/spf13/cobra"
+)
+
+// shutdownTimeout bounds how long the receiver waits for in-flight
+// deliveries when it is stopped.
+const shutdownTimeout = 10 * time.Second
+
+// eventPrinter writes one line per received event. Deliveries are handled
+// concurrently, so writes are serialised.
+type eventPrinter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format string
+}
+
+// Print writes an event and, for transaction events that still exist, the
+// transaction it refers to.
+func (p *eventPrinter) Print(event models.WebhookEvent, tx *models.Transaction) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.format == "json" {
+		data, err := json.Marshal(struct {
+			Event       models.WebhookEvent `json:"event"`
+			Transaction *models.Transaction `json:"transaction,omitempty"`
+		}{event, tx})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", data)
+		return err
+	}
+
+	fields := []string{event.Attributes.CreatedAt.Format(time.RFC3339), event.Attributes.EventType, event.ID}
+	if id := eventTransactionID(event); id != "" {
+		fields = append(fields, id)
+	}
+	if tx != nil {
+		fields = append(fields,
+			fmt.Sprintf("%q", tx.Attributes.Description),
+			tx.Attributes.Amount.Value,
+			tx.Attributes.Amount.CurrencyCode,
+			tx.Attributes.Status,
+		)
+	}
+	_, err := fmt.Fprintln(p.w, strings.Join(fields, " "))
+	return err
+}
+
+// eventTransactionID returns the ID of the transaction an event refers to,
+// if any.
+func eventTransactionID(event models.WebhookEvent) string {
+	if event.Relations.Transaction != nil {
+		return event.Relations.Transaction.Data.ID
+	}
+	return ""
+}
+
+// eventTransaction fetches the transaction an event refers to. Deleted
+// transactions can no longer be fetched, and PING events have none, so nil
+// is returned for those.
+func eventTransaction(ctx context.Context, client api.UpClient, event models.WebhookEvent) (*models.Transaction, error) {
+	switch event.Attributes.EventType {
+	case models.EventTransactionCreated, models.EventTransactionSettled:
+	default:
+		return nil, nil
+	}
+
+	id := eventTransactionID(event)
+	if id == "" {
+		return nil, fmt.Errorf("%s event %s has no transaction", event.Attributes.EventType, event.ID)
+	}
+	tx, err := client.GetTransaction(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching transaction %s: %w", id, err)
+	}
+	return &tx, nil
+}
+
+// loadHooks builds the runner for the hooks declared in the config file,
+// resolving account names to IDs. It returns nil when no hooks are declared.
+func loadHooks(cmd *cobra.Command, client api.UpClient) (*hooks.Runner, error) {
+	var declared []hooks.Hook
+	if _, err := cfg.Decode("hooks", &declared); err != nil {
+		return nil, err
+	}
+	if len(declared) == 0 {
+		return nil, nil
+	}
+
+	for i, h := range declared {
+		if h.Account == "" {
+			continue
+		}
+		account, err := resolveAccount(cmd.Context(), client, h.Account)
+		if err != nil {
+			return nil, fmt.Errorf("hook %q: %w", h.Name, err)
+		}
+		declared[i].Account = account.ID
+	}
+
+	concurrency, _ := cmd.Flags().GetInt("hook-concurrency")
+	return hooks.NewRunner(declared, concurrency, cmd.ErrOrStderr())
+}
+
+// newEventID returns a random UUID, in the form Up uses for event IDs.
+func newEventID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+var (
+	webhooksServeCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Receive webhook events locally",
+		Long: `Run an HTTP server that receives Up webhook deliveries.
+
+Each delivery's X-Up-Authenticity-Signature is checked against the webhook's
+secret key and deliveries with a bad signature are rejected. For
+TRANSACTION_CREATED and TRANSACTION_SETTLED events the transaction is fetched
+from Up and printed alongside the event. If the transaction can't be fetched
+the delivery fails, so Up retries it later.
+
+Hooks declared under "hooks" in the config file are run for matching events
+after they are printed. See the README for the hook format.
+
+The server stops cleanly on Ctrl+C.`,
+		Example: `  upbank-cli webhooks serve --addr :8080 --secret-file up-secret
+  upbank-cli webhooks serve --secret-file up-secret -o json >> events.jsonl`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, _ := cmd.Flags().GetString("addr")
+			secretFile, _ := cmd.Flags().GetString("secret-file")
+			output, _ := cmd.Flags().GetString("output")
+
+			if output != "text" && output != "json" {
+				return fmt.Errorf("invalid output format %q. Use text or json", output)
+			}
+			secret, err := readSecretFile(secretFile)
+			if err != nil {
+				return err
+			}
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			var runner *hooks.Runner
+			if noHooks, _ := cmd.Flags().GetBool("no-hooks"); !noHooks {
+				if runner, err = loadHooks(cmd, client); err != nil {
+					return err
+				}
+			}
+			if runner != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Loaded %d hooks\n", runner.Len())
+			}
+
+			ledgerPath, _ := cmd.Flags().GetString("ledger")
+			ledger, err := webhook.OpenLedger(ledgerPath)
+			if err != nil {
+				return err
+			}
+
+			printer := &eventPrinter{w: cmd.OutOrStdout(), format: output}
+			handler := webhook.Handler(secret, func(ctx context.Context, event models.WebhookEvent) error {
+				tx, err := eventTransaction(ctx, client, event)
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Failed to handle event %s: %v\n", event.ID, err)
+					return err
+				}
+				if err := printer.Print(event, tx); err != nil {
+					return err
+				}
+
+				if runner != nil {
+					// Deleted transactions can't be fetched, so hooks only get the ID
+					hookTx := models.Transaction{ID: eventTransactionID(event)}
+					if tx != nil {
+						hookTx = *tx
+					}
+					runner.Dispatch(ctx, event, hookTx)
+				}
+
+				// Let "webhooks replay" skip this event
+				if err := ledger.Record(webhook.LedgerEntry{
+					EventID:      event.ID,
+					WebhookID:    event.Relations.Webhook.Data.ID,
+					EventType:    event.Attributes.EventType,
+					Acknowledged: time.Now(),
+				}); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %v\n", err)
+				}
+				return nil
+			})
+
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			srv := &http.Server{
+				Handler:           handler,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Listening for webhook events on %s\n", ln.Addr())
+
+			errc := make(chan error, 1)
+			go func() { errc <- srv.Serve(ln) }()
+
+			select {
+			case err := <-errc:
+				return err
+			case <-cmd.Context().Done():
+			}
+
+			// Let in-flight deliveries finish before exiting
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := srv.Shutdown(ctx); err != nil {
+				return err
+			}
+			if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			if runner != nil {
+				// Hooks are bounded by their own timeouts
+				runner.Wait()
+			}
+			fmt.Fprintln(cmd.ErrOrStderr(), "Stopped")
+			return nil
+		},
+	}
+
+	webhooksSendCmd = &cobra.Command{
+		Use:   "send",
+		Short: "Send a signed test event to a local receiver",
+		Long: `Send a webhook event signed with a secret key to a URL, the same way Up
+delivers events. Use it to test "webhooks serve" or any other receiver without
+waiting for real transactions.`,
+		Example: `  upbank-cli webhooks send --to http://localhost:8080 --secret-file up-secret
+  upbank-cli webhooks send --to http://localhost:8080 --secret-file up-secret --event TRANSACTION_CREATED --transaction <transaction-id>`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			to, _ := cmd.Flags().GetString("to")
+			secretFile, _ := cmd.Flags().GetString("secret-file")
+			eventType, _ := cmd.Flags().GetString("event")
+			transactionID, _ := cmd.Flags().GetString("transaction")
+			webhookID, _ := cmd.Flags().GetString("webhook")
+
+			eventType = strings.ToUpper(eventType)
+			switch eventType {
+			case models.EventPing:
+			case models.EventTransactionCreated, models.EventTransactionSettled, models.EventTransactionDeleted:
+				if transactionID == "" {
+					return fmt.Errorf("--transaction is required for %s events", eventType)
+				}
+			default:
+				return fmt.Errorf("invalid event type %q. Use PING, TRANSACTION_CREATED, TRANSACTION_SETTLED or TRANSACTION_DELETED", eventType)
+			}
+
+			secret, err := readSecretFile(secretFile)
+			if err != nil {
+				return err
+			}
+
+			// Build the payload as raw JSON, matching what Up delivers
+			relationships := map[string]any{
+				"webhook": map[string]any{
+					"data": map[string]string{"type": "webhooks", "id": webhookID},
+				},
+			}
+			if transactionID != "" {
+				relationships["transaction"] = map[string]any{
+					"data": map[string]string{"type": "transactions", "id": transactionID},
+				}
+			}
+			eventID := newEventID()
+			body, err := json.Marshal(map[string]any{
+				"data": map[string]any{
+					"type": "webhook-events",
+					"id":   eventID,
+					"attributes": map[string]any{
+						"eventType": eventType,
+						"createdAt": time.Now().Format(time.RFC3339),
+					},
+					"relationships": relationships,
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := webhook.Deliver(cmd.Context(), webhookHTTPClient, to, secret, body); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Sent %s event %s\n", eventType, eventID)
+			return nil
+		},
+	}
+)
+
+func init() {
+	webhooksServeCmd.Flags().String("addr", ":8080", "Address to listen on")
+	webhooksServeCmd.Flags().String("secret-file", "", "File containing the webhook secret key (required)")
+	webhooksServeCmd.Flags().StringP("output", "o", "text", "Output format: text or json (one object per line)")
+	webhooksServeCmd.Flags().String("ledger", defaultLedgerPath(), "Path to the delivery ledger of acknowledged events, used by \"webhooks replay\"")
+	webhooksServeCmd.Flags().Int("hook-concurrency", 4, "Maximum number of hooks to run at once")
+	webhooksServeCmd.Flags().Bool("no-hooks", false, "Don't run the hooks declared in the config file")
+	_ = webhooksServeCmd.MarkFlagRequired("secret-file")
+
+	webhooksSendCmd.Flags().String("to", "http://localhost:8080", "URL to send the event to")
+	webhooksSendCmd.Flags().String("secret-file", "", "File containing the secret key to sign the event with (required)")
+	webhooksSendCmd.Flags().String("event", models.EventPing, "Event type: PING, TRANSACTION_CREATED, TRANSACTION_SETTLED or TRANSACTION_DELETED")
+	webhooksSendCmd.Flags().String("transaction", "", "Transaction ID the event refers to")
+	webhooksSendCmd.Flags().String("webhook", "", "Webhook ID the event claims to come from")
+	_ = webhooksSendCmd.MarkFlagRequired("secret-file")
+
+	webhooksCmd.AddCommand(webhooksServeCmd, webhooksSendCmd)
+}
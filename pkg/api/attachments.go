@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"time"
+	"upbank-cli/pkg/models"
+)
+
+// Attachments returns an iterator over every attachment, fetching pages
+// lazily as the caller consumes them. A pageSize of zero uses the API
+// default.
+func (c *Client) Attachments(ctx context.Context, pageSize int) iter.Seq2[models.Attachment, error] {
+	if err := validatePageSize(pageSize); err != nil {
+		return invalid[models.Attachment](err)
+	}
+	return paginate[models.Attachment](ctx, c, "attachments", c.buildURL("/attachments", pageQuery(pageSize)))
+}
+
+// ListAttachments collects every attachment across all pages.
+func (c *Client) ListAttachments(ctx context.Context) ([]models.Attachment, error) {
+	return collect(c.Attachments(ctx, 0))
+}
+
+// GetAttachment retrieves a single attachment by ID, with a freshly signed
+// file URL.
+func (c *Client) GetAttachment(ctx context.Context, id string) (models.Attachment, error) {
+	var response models.AttachmentResponse
+	if err := c.get(ctx, c.buildURL("/attachments/"+url.PathEscape(id), nil), &response); err != nil {
+		return models.Attachment{}, err
+	}
+	return response.Data, nil
+}
+
+// DownloadAttachment writes the file of an attachment to w. The file URL is
+// pre-signed and expires, so the attachment is fetched again first if its
+// URL is about to expire, and once more if the file host refuses the URL
+// with 403 Forbidden. The API key is not sent to the file host. Like API
+// requests, downloads are rate limited, bound by the per-request timeout and
+// retried on transient failures, but only until the first byte has been
+// written to w.
+func (c *Client) DownloadAttachment(ctx context.Context, attachment models.Attachment, w io.Writer) (int64, error) {
+	expiresAt := attachment.Attributes.FileURLExpiresAt
+	refreshed := false
+	if attachment.Attributes.FileURL == nil || (!expiresAt.IsZero() && time.Until(expiresAt) < time.Minute) {
+		var err error
+		if attachment, err = c.GetAttachment(ctx, attachment.ID); err != nil {
+			return 0, err
+		}
+		refreshed = true
+	}
+
+	for {
+		written, err := c.downloadFile(ctx, attachment, w)
+		// A refused URL may have expired or been revoked early, which a
+		// freshly signed one fixes; retrying the same URL never would
+		var apiErr *Error
+		if refreshed || written > 0 || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+			return written, err
+		}
+		if attachment, err = c.GetAttachment(ctx, attachment.ID); err != nil {
+			return 0, err
+		}
+		refreshed = true
+	}
+}
+
+// downloadFile downloads the file of an attachment from its current file
+// URL, retrying transient failures.
+func (c *Client) downloadFile(ctx context.Context, attachment models.Attachment, w io.Writer) (int64, error) {
+	if attachment.Attributes.FileURL == nil {
+		return 0, errors.New("attachment has no file")
+	}
+	fileURL := *attachment.Attributes.FileURL
+
+	// The query of the file URL is its signature, so keep it out of logs
+	logURL := fileURL
+	if u, err := url.Parse(fileURL); err == nil {
+		logURL = redactQuery(*u)
+	}
+
+	var written int64
+	err := c.withRetries(ctx, http.MethodGet, logURL, func() error {
+		n, err := c.downloadOnce(ctx, fileURL, w)
+		written += n
+		if err != nil && written > 0 {
+			// Part of the file has been written, so starting over would
+			// corrupt it. %v rather than %w keeps it from being retried.
+			return fmt.Errorf("%v (after writing %d bytes)", err, written)
+		}
+		return err
+	})
+	return written, err
+}
+
+// downloadOnce performs a single attempt of DownloadAttachment, bound to the
+// client's per-request timeout.
+func (c *Client) downloadOnce(ctx context.Context, fileURL string, w io.Writer) (int64, error) {
+	if c.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.requestTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error downloading attachment: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("error downloading attachment: %w", newError(resp))
+	}
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("error downloading attachment: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,387 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrNotRecorded is returned when replaying a request that is not in the
+// cassette.
+var ErrNotRecorded = errors.New("no recorded response")
+
+// Interaction is a single request/response pair stored in a cassette. Each
+// interaction is saved as its own numbered JSON file in the cassette
+// directory. BaseURL is the API URL it was recorded against; API URLs in the
+// response body, such as pagination links, are rewritten to the replaying
+// API URL.
+type Interaction struct {
+	BaseURL  string           `json:"baseURL,omitempty"`
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the part of a request used to match it on replay. The
+// URL of an API request holds only the path and query relative to the API
+// URL, so a cassette recorded against one API URL can be replayed against
+// any other. Requests outside the API, such as attachment downloads, keep
+// their full URL but not their query, which may be a signature granting
+// access to the file.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response as stored in a cassette, after scrubbing.
+// Bodies that aren't valid UTF-8, such as attachment files, are stored
+// base64 encoded, with Encoding set to "base64".
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+	Encoding   string      `json:"encoding,omitempty"`
+}
+
+// newRecordedResponse returns body as stored in a cassette, scrubbing it or
+// encoding it if it's binary.
+func newRecordedResponse(statusCode int, header http.Header, body []byte, baseURL string) RecordedResponse {
+	r := RecordedResponse{StatusCode: statusCode, Header: header}
+	if utf8.Valid(body) {
+		r.Body = scrubBody(string(body), baseURL)
+	} else {
+		r.Body = base64.StdEncoding.EncodeToString(body)
+		r.Encoding = "base64"
+	}
+	return r
+}
+
+// body returns the response body as it was received, with API URLs under
+// recordedBase moved to baseURL.
+func (r RecordedResponse) body(recordedBase, baseURL string) ([]byte, error) {
+	switch r.Encoding {
+	case "":
+		if recordedBase == "" || baseURL == "" {
+			return []byte(r.Body), nil
+		}
+		return []byte(strings.ReplaceAll(r.Body, recordedBase, baseURL)), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(r.Body)
+	default:
+		return nil, fmt.Errorf("unknown body encoding %q", r.Encoding)
+	}
+}
+
+// key identifies requests that are answered by the same recordings.
+func (r RecordedRequest) key() string {
+	return r.Method + " " + r.URL + " " + r.Body
+}
+
+// WithRecorder saves every request/response pair to a cassette in dir,
+// scrubbing credentials and account numbers. Requests still go to the API.
+func WithRecorder(dir string) Option {
+	return func(c *Client) {
+		c.recordDir = dir
+	}
+}
+
+// WithReplay answers requests from a cassette in dir instead of the API. No
+// network requests are made and no API token is needed.
+func WithReplay(dir string) Option {
+	return func(c *Client) {
+		c.replayDir = dir
+	}
+}
+
+// recordedRequest reads the matching details of req, restoring its body for
+// the next RoundTripper. Requests under baseURL are keyed on the rest of
+// their URL; requests outside it keep their scheme and host but lose their
+// query.
+func recordedRequest(req *http.Request, baseURL string) (RecordedRequest, error) {
+	r := RecordedRequest{Method: req.Method, URL: req.URL.RequestURI()}
+	switch {
+	case baseURL == "":
+	case strings.HasPrefix(req.URL.String(), baseURL):
+		r.URL = strings.TrimPrefix(req.URL.String(), baseURL)
+	default:
+		r.URL = redactQuery(*req.URL)
+	}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return r, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		r.Body = string(body)
+	}
+	return r, nil
+}
+
+// Recorder is an http.RoundTripper that passes requests on to Next and saves
+// each interaction to Dir. URLs outside BaseURL, in requests and in response
+// bodies, are recorded without their query; an empty BaseURL treats every
+// URL as part of the API.
+type Recorder struct {
+	Dir     string
+	Next    http.RoundTripper
+	BaseURL string
+
+	mu  sync.Mutex
+	seq int
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := recordedRequest(req, r.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Scrubbing can change the body length, and dates would make cassettes
+	// differ between recordings
+	header := resp.Header.Clone()
+	for _, h := range []string{"Set-Cookie", "Authorization", "Date", "Content-Length"} {
+		header.Del(h)
+	}
+	recorded.Body = scrubBody(recorded.Body, r.BaseURL)
+	interaction := Interaction{
+		BaseURL:  r.BaseURL,
+		Request:  recorded,
+		Response: newRecordedResponse(resp.StatusCode, header, body, r.BaseURL),
+	}
+	if err := r.save(interaction); err != nil {
+		return nil, fmt.Errorf("error recording cassette: %w", err)
+	}
+	return resp, nil
+}
+
+func (r *Recorder) save(interaction Interaction) error {
+	data, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := os.MkdirAll(r.Dir, 0o755); err != nil {
+		return err
+	}
+	// Append to a cassette recorded by earlier commands
+	if r.seq == 0 {
+		existing, _ := filepath.Glob(filepath.Join(r.Dir, "*.json"))
+		r.seq = len(existing)
+	}
+	r.seq++
+	name := fmt.Sprintf("%04d-%s.json", r.seq, strings.ToLower(interaction.Request.Method))
+	return os.WriteFile(filepath.Join(r.Dir, name), append(data, '\n'), 0o644)
+}
+
+// Replayer is an http.RoundTripper that answers requests from a cassette.
+// Requests are matched on method, path, query and body. When the same
+// request was recorded several times, the responses are served in the order
+// they were recorded. Requests outside the API only match if they were
+// recorded with the same scheme and host.
+type Replayer struct {
+	BaseURL string
+
+	mu           sync.Mutex
+	interactions map[string][]Interaction
+}
+
+// NewReplayer loads the cassette in dir.
+func NewReplayer(dir string) (*Replayer, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recorded interactions in cassette %s", dir)
+	}
+	// Numbered file names keep the recording order
+	sort.Strings(files)
+
+	r := &Replayer{interactions: make(map[string][]Interaction)}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading cassette: %v", err)
+		}
+		var interaction Interaction
+		if err := json.Unmarshal(data, &interaction); err != nil {
+			return nil, fmt.Errorf("error parsing cassette file %s: %v", file, err)
+		}
+		key := interaction.Request.key()
+		r.interactions[key] = append(r.interactions[key], interaction)
+	}
+	return r, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := recordedRequest(req, r.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	recorded.Body = scrubBody(recorded.Body, r.BaseURL)
+
+	r.mu.Lock()
+	key := recorded.key()
+	responses := r.interactions[key]
+	if len(responses) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("%w for %s %s", ErrNotRecorded, req.Method, recorded.URL)
+	}
+	recording := responses[0].Response
+	recordedBase := responses[0].BaseURL
+	// The last response keeps answering once the others are used up
+	if len(responses) > 1 {
+		r.interactions[key] = responses[1:]
+	}
+	r.mu.Unlock()
+
+	body, err := recording.body(recordedBase, r.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette response for %s %s: %v", req.Method, recorded.URL, err)
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(recording.StatusCode) + " " + http.StatusText(recording.StatusCode),
+		StatusCode:    recording.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recording.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// secretFields lists JSON fields that hold secrets, such as the key
+// returned when a webhook is created.
+var secretFields = map[string]bool{"secretKey": true}
+
+// bsbPattern matches BSB numbers, e.g. 123-456.
+var bsbPattern = regexp.MustCompile(`\d{3}-\d{3}`)
+
+// digitRunPattern matches runs of digits long enough to be account or card
+// numbers.
+var digitRunPattern = regexp.MustCompile(`\d{6,}`)
+
+// scrubBody removes secrets and account numbers from the string values of a
+// JSON body. Numbers, such as amounts in base units, and API URLs, such as
+// pagination links, are kept. Other URLs, such as pre-signed file URLs, lose
+// their query. Bodies that aren't JSON are stored as is.
+func scrubBody(body, baseURL string) string {
+	if body == "" {
+		return body
+	}
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return body
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(scrubValue(v, baseURL)); err != nil {
+		return body
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func scrubValue(v any, baseURL string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if s, ok := field.(string); ok && secretFields[k] {
+				if s != "" {
+					v[k] = "REDACTED"
+				}
+				continue
+			}
+			v[k] = scrubValue(field, baseURL)
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = scrubValue(v[i], baseURL)
+		}
+		return v
+	case string:
+		if strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") {
+			if strings.HasPrefix(v, baseURL) {
+				return v
+			}
+			u, err := url.Parse(v)
+			if err != nil {
+				return "REDACTED"
+			}
+			return redactQuery(*u)
+		}
+		v = replaceStandalone(v, bsbPattern, "000-000")
+		return replaceStandalone(v, digitRunPattern, "")
+	default:
+		return v
+	}
+}
+
+// replaceStandalone replaces matches of re that aren't part of a larger
+// word, number or ID, so that amounts, dates and IDs survive. An empty
+// replacement masks each digit with 0.
+func replaceStandalone(s string, re *regexp.Regexp, replacement string) string {
+	isWordByte := func(b byte) bool {
+		return b == '-' || b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+	}
+	isDigit := func(i int) bool {
+		return i >= 0 && i < len(s) && s[i] >= '0' && s[i] <= '9'
+	}
+	// A dot only joins the match to a decimal, not to the end of a sentence
+	joinedBefore := func(i int) bool {
+		return i > 0 && (isWordByte(s[i-1]) || s[i-1] == '.' && isDigit(i-2))
+	}
+	joinedAfter := func(i int) bool {
+		return i < len(s) && (isWordByte(s[i]) || s[i] == '.' && isDigit(i+1))
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringIndex(s, -1) {
+		if joinedBefore(m[0]) || joinedAfter(m[1]) {
+			continue
+		}
+		b.WriteString(s[last:m[0]])
+		if replacement == "" {
+			b.WriteString(strings.Repeat("0", m[1]-m[0]))
+		} else {
+			b.WriteString(replacement)
+		}
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScrubBody(t *testing.T) {
+	const base = "https://api.example.com/api/v1"
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"empty", ``, ``},
+		{"not JSON", `BSB 123-456`, `BSB 123-456`},
+		{"secret key", `{"secretKey":"s3cr3t"}`, `{"secretKey":"REDACTED"}`},
+		{"empty secret key", `{"secretKey":""}`, `{"secretKey":""}`},
+		{"null secret key", `{"secretKey":null}`, `{"secretKey":null}`},
+		{"nested secret key", `{"data":[{"attributes":{"secretKey":"abc"}}]}`, `{"data":[{"attributes":{"secretKey":"REDACTED"}}]}`},
+		{"BSB", `{"description":"Transfer to 123-456 12345678"}`, `{"description":"Transfer to 000-000 00000000"}`},
+		{"digit run", `{"rawText":"CARD 4567891234"}`, `{"rawText":"CARD 0000000000"}`},
+		{"short digit run", `{"description":"Store 12345"}`, `{"description":"Store 12345"}`},
+		{"amount", `{"value":"1234567.89","valueInBaseUnits":123456789}`, `{"value":"1234567.89","valueInBaseUnits":123456789}`},
+		{"date", `{"createdAt":"2024-01-15T09:00:00+11:00"}`, `{"createdAt":"2024-01-15T09:00:00+11:00"}`},
+		{"ID", `{"id":"a1b2c3d4-123456-7890123"}`, `{"id":"a1b2c3d4-123456-7890123"}`},
+		{"API link", `{"next":"` + base + `/transactions?page%5Bafter%5D=1234567890"}`, `{"next":"` + base + `/transactions?page%5Bafter%5D=1234567890"}`},
+		{"file URL", `{"fileURL":"https://files.example.com/receipt.jpg?X-Amz-Signature=abc123"}`, `{"fileURL":"https://files.example.com/receipt.jpg?REDACTED"}`},
+		{"file URL without query", `{"fileURL":"https://files.example.com/receipt.jpg"}`, `{"fileURL":"https://files.example.com/receipt.jpg"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scrubBody(tt.body, base); got != tt.want {
+				t.Errorf("scrubBody(%s) = %s, want %s", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceStandalone(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"123456", "000000"},
+		{"acct 123456789 ok", "acct 000000000 ok"},
+		{"12345", "12345"},
+		{"x123456", "x123456"},
+		{"123456.78", "123456.78"},
+		{"0.1234567", "0.1234567"},
+		{"ends in 1234567.", "ends in 0000000."},
+		{"v.1234567", "v.0000000"},
+		{"2024-123456", "2024-123456"},
+		{"id_1234567", "id_1234567"},
+		{"(1234567)", "(0000000)"},
+		{"111111 and 2222222", "000000 and 0000000"},
+	}
+	for _, tt := range tests {
+		if got := replaceStandalone(tt.s, digitRunPattern, ""); got != tt.want {
+			t.Errorf("replaceStandalone(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+
+	if got := replaceStandalone("BSB 123-456.", bsbPattern, "000-000"); got != "BSB 000-000." {
+		t.Errorf("replaceStandalone BSB = %q", got)
+	}
+	if got := replaceStandalone("1123-4567", bsbPattern, "000-000"); got != "1123-4567" {
+		t.Errorf("replaceStandalone BSB in a longer number = %q", got)
+	}
+}
+
+// cassetteServer serves an attachment whose pre-signed file is outside the
+// API, and a webhook creation returning a secret key.
+func cassetteServer(t *testing.T, file []byte) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/attachments/att-1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data":{"type":"attachments","id":"att-1","attributes":{"fileURL":%q,"fileURLExpiresAt":"2099-01-01T00:00:00Z"}}}`,
+			srv.URL+"/files/receipt.jpg?X-Amz-Signature=s1gnature")
+	})
+	mux.HandleFunc("GET /files/receipt.jpg", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Error("API token sent to the file host")
+		}
+		if r.URL.Query().Get("X-Amz-Signature") != "s1gnature" {
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write(file)
+	})
+	mux.HandleFunc("POST /api/v1/webhooks", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"data":{"type":"webhooks","id":"wh-1","attributes":{"url":"https://example.com/hook","secretKey":"wh-secret-key"}}}`)
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	// Not valid UTF-8, so it can't survive being stored as a string
+	file := []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x80, 0xfe}
+	srv := cassetteServer(t, file)
+
+	recorder, err := NewClient(
+		WithToken("up:yeah:record", "test"),
+		WithBaseURL(srv.URL+"/api/v1"),
+		WithRecorder(dir),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhook, err := recorder.CreateWebhook(ctx, "https://example.com/hook", "")
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if webhook.Attributes.SecretKey == nil || *webhook.Attributes.SecretKey != "wh-secret-key" {
+		t.Fatalf("recording changed the live response: %+v", webhook.Attributes)
+	}
+	attachment, err := recorder.GetAttachment(ctx, "att-1")
+	if err != nil {
+		t.Fatalf("GetAttachment: %v", err)
+	}
+	var recorded bytes.Buffer
+	if _, err := recorder.DownloadAttachment(ctx, attachment, &recorded); err != nil {
+		t.Fatalf("DownloadAttachment: %v", err)
+	}
+	if !bytes.Equal(recorded.Bytes(), file) {
+		t.Fatalf("recorded download = %x, want %x", recorded.Bytes(), file)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 3 {
+		t.Fatalf("got %d cassette files, want 3", len(files))
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range []string{"up:yeah:record", "wh-secret-key", "s1gnature"} {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%s contains %q:\n%s", filepath.Base(f), secret, data)
+			}
+		}
+	}
+
+	// Replay against another API URL with the server gone
+	srv.Close()
+	var logs bytes.Buffer
+	replayer, err := NewClient(
+		WithToken("", ""),
+		WithBaseURL("http://replay.invalid/api/v1"),
+		WithReplay(dir),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhook, err = replayer.CreateWebhook(ctx, "https://example.com/hook", "")
+	if err != nil {
+		t.Fatalf("replayed CreateWebhook: %v", err)
+	}
+	if webhook.ID != "wh-1" || webhook.Attributes.SecretKey == nil || *webhook.Attributes.SecretKey != "REDACTED" {
+		t.Errorf("replayed webhook = %+v", webhook)
+	}
+	attachment, err = replayer.GetAttachment(ctx, "att-1")
+	if err != nil {
+		t.Fatalf("replayed GetAttachment: %v", err)
+	}
+	if want := srv.URL + "/files/receipt.jpg?REDACTED"; *attachment.Attributes.FileURL != want {
+		t.Errorf("replayed fileURL = %s, want %s", *attachment.Attributes.FileURL, want)
+	}
+	var replayed bytes.Buffer
+	if _, err := replayer.DownloadAttachment(ctx, attachment, &replayed); err != nil {
+		t.Fatalf("replayed DownloadAttachment: %v", err)
+	}
+	if !bytes.Equal(replayed.Bytes(), file) {
+		t.Errorf("replayed download = %x, want %x", replayed.Bytes(), file)
+	}
+
+	// A request missing from the cassette fails without being retried
+	_, err = replayer.GetAttachment(ctx, "att-2")
+	if !errors.Is(err, ErrNotRecorded) {
+		t.Fatalf("GetAttachment(att-2) error = %v, want ErrNotRecorded", err)
+	}
+	if strings.Contains(logs.String(), "retrying request") {
+		t.Errorf("missing request was retried:\n%s", logs.String())
+	}
+}
+
+func TestReplayerRepeatsLastResponse(t *testing.T) {
+	dir := t.TempDir()
+	for i, body := range []string{`{"n":1}`, `{"n":2}`} {
+		data := fmt.Sprintf(`{"request":{"method":"GET","url":"/util/ping"},"response":{"statusCode":200,"body":%q}}`, body)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%04d-get.json", i+1)), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`{"n":1}`, `{"n":2}`, `{"n":2}`} {
+		req := httptest.NewRequest(http.MethodGet, "http://replay.invalid/util/ping", nil)
+		resp, err := r.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body bytes.Buffer
+		body.ReadFrom(resp.Body)
+		if body.String() != want {
+			t.Errorf("body = %s, want %s", body.String(), want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"upbank-cli/pkg/models"
+)
+
+// ListCategories retrieves every category. If parent is not empty, only the
+// children of that parent category are returned.
+func (c *Client) ListCategories(ctx context.Context, parent string) ([]models.Category, error) {
+	query := url.Values{}
+	if parent != "" {
+		query.Set("filter[parent]", parent)
+	}
+
+	var response models.CategoriesResponse
+	if err := c.get(ctx, c.buildURL("/categories", query), &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// GetCategory retrieves a single category by ID.
+func (c *Client) GetCategory(ctx context.Context, id string) (models.Category, error) {
+	var response models.CategoryResponse
+	if err := c.get(ctx, c.buildURL("/categories/"+url.PathEscape(id), nil), &response); err != nil {
+		return models.Category{}, err
+	}
+	return response.Data, nil
+}
+
+// Categorize assigns the category with categoryID to a transaction. An empty
+// categoryID removes the transaction's category. Only child categories can be
+// assigned, and only to transactions that are categorizable.
+func (c *Client) Categorize(ctx context.Context, transactionID, categoryID string) error {
+	var body struct {
+		Data *resourceIdentifier `json:"data"`
+	}
+	if categoryID != "" {
+		body.Data = &resourceIdentifier{Type: "categories", ID: categoryID}
+	}
+
+	url := c.buildURL("/transactions/"+url.PathEscape(transactionID)+"/relationships/category", nil)
+	return c.do(ctx, http.MethodPatch, url, body, nil)
+}
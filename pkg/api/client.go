@@ -1,128 +1,332 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"iter"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
+	"time"
 	"upbank-cli/pkg/models"
 )
 
-const baseURL = "https://api.up.com.au/api/v1"
+// DefaultBaseURL is the root of the Up API. It can be overridden with the
+// UPBANK_API_URL environment variable or WithBaseURL.
+const DefaultBaseURL = "https://api.up.com.au/api/v1"
+
+// DefaultUserAgent is sent with every request unless WithUserAgent is used.
+const DefaultUserAgent = "upbank-cli"
+
+// DefaultRequestTimeout bounds a single round trip to the Up API, including
+// reading the response body.
+const DefaultRequestTimeout = 30 * time.Second
 
 type Client struct {
-	httpClient *http.Client
-	apiKey     string
+	httpClient     *http.Client
+	apiKey         string
+	tokenSource    string
+	baseURL        string
+	userAgent      string
+	transport      http.RoundTripper
+	caBundle       string
+	proxyURL       string
+	recordDir      string
+	replayDir      string
+	requestTimeout time.Duration
+	retry          RetryPolicy
+	retriesUsed    retryBudget
+	limiter        *rateLimiter
+	logger         *slog.Logger
+	dumpDir        string
+}
+
+// resourceIdentifier refers to a resource in the body of a relationship
+// update.
+type resourceIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Option configures optional behaviour of a Client.
+type Option func(*Client)
+
+// WithRequestTimeout sets the deadline applied to each individual HTTP
+// request. A zero or negative duration disables the per-request deadline,
+// leaving only the deadline of the caller's context.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.requestTimeout = d
+	}
 }
 
-func NewClient() (*Client, error) {
-	apiKey := os.Getenv("UPBANK_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("UPBANK_API_KEY environment variable is not set")
+// ErrNoToken is returned by NewClient when no API token is set.
+var ErrNoToken = errors.New("no API token set")
+
+// TokenEnvVars lists the environment variables the API token is read from,
+// in order of precedence. UPBANK_API_KEY is the name used by earlier
+// releases.
+var TokenEnvVars = []string{"UPBANK_API_TOKEN", "UPBANK_API_KEY"}
+
+// TokenFromEnv returns the API token from the first of TokenEnvVars that is
+// set, along with the name of that variable.
+func TokenFromEnv() (token, source string) {
+	for _, name := range TokenEnvVars {
+		if token := os.Getenv(name); token != "" {
+			return token, name
+		}
 	}
+	return "", ""
+}
 
-	return &Client{
-		httpClient: &http.Client{},
-		apiKey:     apiKey,
-	}, nil
+// WithToken sets the API token, overriding the environment. source describes
+// where the token came from, as reported by TokenSource.
+func WithToken(token, source string) Option {
+	return func(c *Client) {
+		c.apiKey = token
+		c.tokenSource = source
+	}
 }
 
-func (c *Client) GetAccounts(params map[string]string) ([]models.Account, error) {
-	url := fmt.Sprintf("%s/accounts", baseURL)
-	
-	// Build query string if params exist
-	if len(params) > 0 {
-		var queryParts []string
-		for key, value := range params {
-			queryParts = append(queryParts, fmt.Sprintf("%s=%s", key, value))
+func NewClient(opts ...Option) (*Client, error) {
+	apiKey, tokenSource := TokenFromEnv()
+
+	baseURL := os.Getenv("UPBANK_API_URL")
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	c := &Client{
+		apiKey:         apiKey,
+		tokenSource:    tokenSource,
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		userAgent:      DefaultUserAgent,
+		requestTimeout: DefaultRequestTimeout,
+		retry:          DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	// Replayed cassettes never reach the API, so they need no token
+	if c.apiKey == "" && c.replayDir != "" {
+		c.apiKey, c.tokenSource = "replay", "cassette "+c.replayDir
+	}
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("%w. Export UPBANK_API_TOKEN with your Up personal access token", ErrNoToken)
+	}
+
+	transport, err := c.buildTransport()
+	if err != nil {
+		return nil, err
+	}
+	c.httpClient = &http.Client{Transport: transport}
+	return c, nil
+}
+
+// BaseURL returns the API root the client sends requests to.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// TokenSource describes where the API token came from, such as the name of
+// the environment variable.
+func (c *Client) TokenSource() string {
+	return c.tokenSource
+}
+
+// get performs an authenticated GET request and decodes the JSON response
+// into v.
+func (c *Client) get(ctx context.Context, url string, v any) error {
+	return c.do(ctx, http.MethodGet, url, nil, v)
+}
+
+// do performs an authenticated request, sending body as JSON when it is not
+// nil and decoding the JSON response into v when v is not nil. Rate-limited
+// and transient failures are retried according to the client's retry policy.
+func (c *Client) do(ctx context.Context, method, url string, body, v any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("error encoding request: %w", err)
+		}
+	}
+
+	return c.withRetries(ctx, method, url, func() error {
+		return c.doOnce(ctx, method, url, payload, v)
+	})
+}
+
+// withRetries calls attempt, waiting for the rate limiter before each call,
+// until it succeeds or fails in a way the retry policy doesn't retry. url is
+// only used for logging.
+func (c *Client) withRetries(ctx context.Context, method, url string, attempt func() error) error {
+	for n := 1; ; n++ {
+		if err := c.limiter.wait(ctx); err != nil {
+			return err
+		}
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+		if n > c.retry.MaxRetries || !shouldRetry(ctx, method, err) || !c.retriesUsed.take(c.retry.Budget) {
+			return err
+		}
+		delay := c.retry.backoff(n, err)
+		if c.logger != nil {
+			c.logger.DebugContext(ctx, "retrying request", "method", method, "url", url, "attempt", n, "delay", delay, "error", err)
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
 		}
-		url = fmt.Sprintf("%s?%s", url, strings.Join(queryParts, "&"))
 	}
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// newRequest builds an authenticated API request with a JSON payload, if
+// any.
+func (c *Client) newRequest(ctx context.Context, method, url string, payload []byte) (*http.Request, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
 	}
+	return req, nil
+}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+// doOnce performs a single attempt of do. The request is bound to ctx and to
+// the client's per-request timeout.
+func (c *Client) doOnce(ctx context.Context, method, url string, payload []byte, v any) error {
+	if c.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.requestTimeout)
+		defer cancel()
+	}
 
-	resp, err := c.httpClient.Do(req)
+	req, err := c.newRequest(ctx, method, url, payload)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %v", err)
+		return err
 	}
-	defer func() {
-		if closeErr := resp.Body.Close(); closeErr != nil {
-			// Log the error but don't return it since we're in a defer
-			fmt.Printf("Error closing response body: %v\n", closeErr)
-		}
-	}()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
 	}
+	defer c.closeBody(ctx, url, resp)
 
-	var response models.AccountsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("error decoding response: %v", err)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newError(resp)
 	}
 
-	return response.Data, nil
+	if v == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	return nil
 }
 
-func (c *Client) GetTransactions(params map[string]string) ([]models.Transaction, error) {
-	url := fmt.Sprintf("%s/transactions", baseURL)
-	
-	// Build query string if params exist
-	if len(params) > 0 {
-		var queryParts []string
-		for key, value := range params {
-			queryParts = append(queryParts, fmt.Sprintf("%s=%s", key, value))
+// closeBody closes the body of a response to url, reporting but not
+// returning a failure, as it runs deferred. Stdout may carry CSV or JSON
+// output, so it is never written there.
+func (c *Client) closeBody(ctx context.Context, url string, resp *http.Response) {
+	if err := resp.Body.Close(); err != nil {
+		if c.logger != nil {
+			c.logger.WarnContext(ctx, "error closing response body", "url", url, "error", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error closing response body: %v\n", err)
 		}
-		url = fmt.Sprintf("%s?%s", url, strings.Join(queryParts, "&"))
 	}
+}
 
-	var allTransactions []models.Transaction
-	for {
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return nil, fmt.Errorf("error creating request: %v", err)
-		}
+// buildURL appends query to the endpoint path. url.Values encodes keys and
+// values and sorts by key, so the same filter always yields the same URL.
+func (c *Client) buildURL(path string, query url.Values) string {
+	if len(query) == 0 {
+		return c.baseURL + path
+	}
+	return c.baseURL + path + "?" + query.Encode()
+}
 
-		req.Header.Set("Authorization", "Bearer "+c.apiKey)
-		req.Header.Set("Accept", "application/json")
+// Accounts returns an iterator over the accounts matching filter, fetching
+// pages lazily as the caller consumes them.
+func (c *Client) Accounts(ctx context.Context, filter AccountFilter) iter.Seq2[models.Account, error] {
+	if err := filter.Validate(); err != nil {
+		return invalid[models.Account](err)
+	}
+	return paginate[models.Account](ctx, c, "accounts", c.buildURL("/accounts", filter.Values()))
+}
 
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("error making request: %v", err)
-		}
+// GetAccounts collects every account matching filter across all pages.
+func (c *Client) GetAccounts(ctx context.Context, filter AccountFilter) ([]models.Account, error) {
+	return collect(c.Accounts(ctx, filter))
+}
 
-		if resp.StatusCode != http.StatusOK {
-			if closeErr := resp.Body.Close(); closeErr != nil {
-				fmt.Printf("Error closing response body: %v\n", closeErr)
-			}
-			return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
-		}
+// GetAccount retrieves a single account by ID.
+func (c *Client) GetAccount(ctx context.Context, id string) (models.Account, error) {
+	var response models.AccountResponse
+	if err := c.get(ctx, c.buildURL("/accounts/"+url.PathEscape(id), nil), &response); err != nil {
+		return models.Account{}, err
+	}
+	return response.Data, nil
+}
 
-		var transactionsResp models.TransactionsResponse
-		if err := json.NewDecoder(resp.Body).Decode(&transactionsResp); err != nil {
-			if closeErr := resp.Body.Close(); closeErr != nil {
-				fmt.Printf("Error closing response body: %v\n", closeErr)
-			}
-			return nil, err
-		}
-		if closeErr := resp.Body.Close(); closeErr != nil {
-			fmt.Printf("Error closing response body: %v\n", closeErr)
-		}
+// GetTransaction retrieves a single transaction by ID.
+func (c *Client) GetTransaction(ctx context.Context, id string) (models.Transaction, error) {
+	var response models.TransactionResponse
+	if err := c.get(ctx, c.buildURL("/transactions/"+url.PathEscape(id), nil), &response); err != nil {
+		return models.Transaction{}, err
+	}
+	return response.Data, nil
+}
 
-		allTransactions = append(allTransactions, transactionsResp.Data...)
+// Transactions returns an iterator over the transactions matching filter.
+// Pages are fetched lazily as the caller consumes them, following links.next
+// until the last page or until the caller stops. Iteration ends after the
+// first error.
+func (c *Client) Transactions(ctx context.Context, filter TransactionFilter) iter.Seq2[models.Transaction, error] {
+	return c.transactions(ctx, "/transactions", filter)
+}
 
-		// Check if there are more pages
-		if transactionsResp.Links.Next == nil {
-			break
-		}
-		url = *transactionsResp.Links.Next
+// AccountTransactions is like Transactions but only yields transactions of
+// the account with the given ID.
+func (c *Client) AccountTransactions(ctx context.Context, accountID string, filter TransactionFilter) iter.Seq2[models.Transaction, error] {
+	return c.transactions(ctx, "/accounts/"+url.PathEscape(accountID)+"/transactions", filter)
+}
+
+// transactions iterates over the pages of a transaction list endpoint.
+func (c *Client) transactions(ctx context.Context, path string, filter TransactionFilter) iter.Seq2[models.Transaction, error] {
+	if err := filter.Validate(); err != nil {
+		return invalid[models.Transaction](err)
 	}
+	return paginate[models.Transaction](ctx, c, "transactions", c.buildURL(path, filter.Values()))
+}
+
+// GetTransactions collects every transaction matching filter. If ctx is
+// cancelled or a page fails part way through, the transactions from the
+// pages already fetched are returned alongside the error.
+func (c *Client) GetTransactions(ctx context.Context, filter TransactionFilter) ([]models.Transaction, error) {
+	return collect(c.Transactions(ctx, filter))
+}
 
-	return allTransactions, nil
-} 
\ No newline at end of file
+// GetAccountTransactions collects every transaction of one account matching
+// filter. Like GetTransactions, partial results are returned with an error.
+func (c *Client) GetAccountTransactions(ctx context.Context, accountID string, filter TransactionFilter) ([]models.Transaction, error) {
+	return collect(c.AccountTransactions(ctx, accountID, filter))
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrorSource identifies the part of the request that caused an error.
+type ErrorSource struct {
+	Parameter string `json:"parameter,omitempty"`
+	Pointer   string `json:"pointer,omitempty"`
+}
+
+// ErrorObject is a single entry of the JSON:API errors array returned by Up.
+type ErrorObject struct {
+	Status string       `json:"status"`
+	Title  string       `json:"title"`
+	Detail string       `json:"detail"`
+	Source *ErrorSource `json:"source,omitempty"`
+}
+
+// Error is returned when the Up API responds with a non-success status code.
+// Use errors.As to inspect it.
+type Error struct {
+	StatusCode int
+	Errors     []ErrorObject
+	// RetryAfter is the delay requested by the server's Retry-After header,
+	// if any.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("unexpected status code: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+
+	messages := make([]string, 0, len(e.Errors))
+	for _, obj := range e.Errors {
+		msg := obj.Detail
+		if msg == "" {
+			msg = obj.Title
+		}
+		// Point at the offending query parameter unless the detail already does
+		if obj.Source != nil && obj.Source.Parameter != "" && !strings.Contains(msg, obj.Source.Parameter) {
+			msg = fmt.Sprintf("%s: %s", obj.Source.Parameter, msg)
+		}
+		messages = append(messages, msg)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// IsAuthError reports whether the request was rejected because the token is
+// missing, invalid or lacks permission.
+func (e *Error) IsAuthError() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// IsValidationError reports whether the request was rejected because of
+// invalid parameters or body.
+func (e *Error) IsValidationError() bool {
+	return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+}
+
+// IsRateLimited reports whether the request was rejected by rate limiting.
+func (e *Error) IsRateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// IsServerError reports whether the Up API failed to handle the request.
+func (e *Error) IsServerError() bool {
+	return e.StatusCode >= http.StatusInternalServerError
+}
+
+// newError builds an Error from a non-success response, decoding the JSON:API
+// errors payload when one is present.
+func newError(resp *http.Response) *Error {
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	// Error payloads are small; cap the read so a misbehaving server can't
+	// make us buffer an arbitrarily large body.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return apiErr
+	}
+
+	var payload struct {
+		Errors []ErrorObject `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		apiErr.Errors = payload.Errors
+	}
+	return apiErr
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		body       string
+		wantErrors int
+		wantMsg    string
+		wantRetry  time.Duration
+	}{
+		{
+			name:       "invalid parameter",
+			status:     http.StatusBadRequest,
+			body:       `{"errors":[{"status":"400","title":"Invalid Parameter","detail":"is not a valid datetime","source":{"parameter":"filter[since]"}}]}`,
+			wantErrors: 1,
+			wantMsg:    "filter[since]: is not a valid datetime",
+		},
+		{
+			name:       "detail naming the parameter",
+			status:     http.StatusBadRequest,
+			body:       `{"errors":[{"status":"400","title":"Invalid Parameter","detail":"filter[status] must be HELD or SETTLED","source":{"parameter":"filter[status]"}}]}`,
+			wantErrors: 1,
+			wantMsg:    "filter[status] must be HELD or SETTLED",
+		},
+		{
+			name:       "title without detail",
+			status:     http.StatusUnauthorized,
+			body:       `{"errors":[{"status":"401","title":"Not Authorized"}]}`,
+			wantErrors: 1,
+			wantMsg:    "Not Authorized",
+		},
+		{
+			name:       "several errors",
+			status:     http.StatusUnprocessableEntity,
+			body:       `{"errors":[{"status":"422","detail":"first"},{"status":"422","detail":"second"}]}`,
+			wantErrors: 2,
+			wantMsg:    "first; second",
+		},
+		{
+			name:       "rate limited",
+			status:     http.StatusTooManyRequests,
+			retryAfter: "7",
+			body:       `{"errors":[{"status":"429","title":"Too Many Requests"}]}`,
+			wantErrors: 1,
+			wantMsg:    "Too Many Requests",
+			wantRetry:  7 * time.Second,
+		},
+		{
+			name:    "not JSON",
+			status:  http.StatusBadGateway,
+			body:    `<html><body>502 Bad Gateway</body></html>`,
+			wantMsg: "unexpected status code: 502 Bad Gateway",
+		},
+		{
+			name:    "empty body",
+			status:  http.StatusServiceUnavailable,
+			wantMsg: "unexpected status code: 503 Service Unavailable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tt.status,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+
+			err := newError(resp)
+			if err.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", err.StatusCode, tt.status)
+			}
+			if len(err.Errors) != tt.wantErrors {
+				t.Errorf("got %d error objects, want %d", len(err.Errors), tt.wantErrors)
+			}
+			if err.Error() != tt.wantMsg {
+				t.Errorf("Error() = %q, want %q", err.Error(), tt.wantMsg)
+			}
+			if err.RetryAfter != tt.wantRetry {
+				t.Errorf("RetryAfter = %s, want %s", err.RetryAfter, tt.wantRetry)
+			}
+		})
+	}
+}
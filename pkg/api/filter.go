@@ -0,0 +1,147 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Values accepted by the Up API for enum filters.
+const (
+	StatusHeld    = "HELD"
+	StatusSettled = "SETTLED"
+
+	AccountTypeSaver         = "SAVER"
+	AccountTypeTransactional = "TRANSACTIONAL"
+	AccountTypeHomeLoan      = "HOME_LOAN"
+
+	OwnershipIndividual = "INDIVIDUAL"
+	OwnershipJoint      = "JOINT"
+)
+
+// MaxPageSize is the largest page[size] accepted by the Up API.
+const MaxPageSize = 100
+
+// ErrInvalidFilter is wrapped by every error returned from a filter's
+// Validate method.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// filterError is a validation failure detected before a request is sent.
+type filterError struct {
+	msg string
+}
+
+func (e *filterError) Error() string { return e.msg }
+func (e *filterError) Unwrap() error { return ErrInvalidFilter }
+
+func invalidFilterf(format string, args ...any) error {
+	return &filterError{msg: fmt.Sprintf(format, args...)}
+}
+
+// TransactionFilter selects the transactions returned by the Up API. Zero
+// fields are not sent.
+type TransactionFilter struct {
+	// Status is HELD or SETTLED.
+	Status string
+	Since  time.Time
+	Until  time.Time
+	// Category is a category ID, e.g. "groceries".
+	Category string
+	// Tag is a tag ID, e.g. "Holiday".
+	Tag string
+	// PageSize is the number of records per page (page[size]).
+	PageSize int
+}
+
+// Validate checks the filter before it is sent to the API.
+func (f TransactionFilter) Validate() error {
+	if err := validateEnum("status", f.Status, StatusHeld, StatusSettled); err != nil {
+		return err
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && f.Until.Before(f.Since) {
+		return invalidFilterf("invalid date range: until (%s) is before since (%s)",
+			f.Until.Format(time.RFC3339), f.Since.Format(time.RFC3339))
+	}
+	return validatePageSize(f.PageSize)
+}
+
+// Values serialises the filter into query parameters.
+func (f TransactionFilter) Values() url.Values {
+	v := url.Values{}
+	if f.Status != "" {
+		v.Set("filter[status]", f.Status)
+	}
+	if !f.Since.IsZero() {
+		v.Set("filter[since]", f.Since.Format(time.RFC3339))
+	}
+	if !f.Until.IsZero() {
+		v.Set("filter[until]", f.Until.Format(time.RFC3339))
+	}
+	if f.Category != "" {
+		v.Set("filter[category]", f.Category)
+	}
+	if f.Tag != "" {
+		v.Set("filter[tag]", f.Tag)
+	}
+	if f.PageSize > 0 {
+		v.Set("page[size]", strconv.Itoa(f.PageSize))
+	}
+	return v
+}
+
+// AccountFilter selects the accounts returned by the Up API. Zero fields are
+// not sent.
+type AccountFilter struct {
+	// AccountType is SAVER, TRANSACTIONAL or HOME_LOAN.
+	AccountType string
+	// OwnershipType is INDIVIDUAL or JOINT.
+	OwnershipType string
+	// PageSize is the number of records per page (page[size]).
+	PageSize int
+}
+
+// Validate checks the filter before it is sent to the API.
+func (f AccountFilter) Validate() error {
+	if err := validateEnum("account type", f.AccountType, AccountTypeSaver, AccountTypeTransactional, AccountTypeHomeLoan); err != nil {
+		return err
+	}
+	if err := validateEnum("ownership type", f.OwnershipType, OwnershipIndividual, OwnershipJoint); err != nil {
+		return err
+	}
+	return validatePageSize(f.PageSize)
+}
+
+// Values serialises the filter into query parameters.
+func (f AccountFilter) Values() url.Values {
+	v := url.Values{}
+	if f.AccountType != "" {
+		v.Set("filter[accountType]", f.AccountType)
+	}
+	if f.OwnershipType != "" {
+		v.Set("filter[ownershipType]", f.OwnershipType)
+	}
+	if f.PageSize > 0 {
+		v.Set("page[size]", strconv.Itoa(f.PageSize))
+	}
+	return v
+}
+
+// validateEnum checks that a non-empty value is one of allowed.
+func validateEnum(name, value string, allowed ...string) error {
+	if value == "" || slices.Contains(allowed, value) {
+		return nil
+	}
+	return invalidFilterf("invalid %s %q. Use one of: %s", name, value, strings.Join(allowed, ", "))
+}
+
+// validatePageSize checks a requested page size.
+func validatePageSize(size int) error {
+	if size < 0 || size > MaxPageSize {
+		return invalidFilterf("invalid page size %d: must be between 1 and %d, or 0 for the API default", size, MaxPageSize)
+	}
+	return nil
+}
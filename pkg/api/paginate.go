@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/url"
+	"strconv"
+	"upbank-cli/pkg/models"
+)
+
+// listPage is the envelope of a page returned by any list endpoint.
+type listPage[T any] struct {
+	Data  []T              `json:"data"`
+	Links models.PageLinks `json:"links"`
+}
+
+// paginate returns an iterator over the records of a list endpoint, starting
+// at url. Pages are fetched lazily as the caller consumes them, following
+// links.next until the last page or until the caller stops. Iteration ends
+// after the first error, which names the resource and the page that failed.
+func paginate[T any](ctx context.Context, c *Client, resource, url string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		for page := 1; ; page++ {
+			var resp listPage[T]
+			if err := c.get(withPage(ctx, page), url, &resp); err != nil {
+				yield(zero, fmt.Errorf("fetching %s page %d: %w", resource, page, err))
+				return
+			}
+
+			for _, record := range resp.Data {
+				if !yield(record, nil) {
+					return
+				}
+			}
+
+			// Check if there are more pages
+			if resp.Links.Next == nil {
+				return
+			}
+			url = *resp.Links.Next
+		}
+	}
+}
+
+// pageQuery returns the query for a list endpoint with only a page size.
+func pageQuery(pageSize int) url.Values {
+	query := url.Values{}
+	if pageSize > 0 {
+		query.Set("page[size]", strconv.Itoa(pageSize))
+	}
+	return query
+}
+
+// collect gathers every record of seq. If iteration fails part way through,
+// the records already gathered are returned alongside the error.
+func collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var all []T
+	for record, err := range seq {
+		if err != nil {
+			return all, err
+		}
+		all = append(all, record)
+	}
+	return all, nil
+}
+
+// invalid returns an iterator that yields err and nothing else.
+func invalid[T any](err error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		yield(zero, err)
+	}
+}
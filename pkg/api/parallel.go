@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sort"
+	"sync"
+	"time"
+	"upbank-cli/pkg/models"
+)
+
+// TransactionSource lists the transactions matching a filter, such as
+// UpClient.Transactions or a closure over UpClient.AccountTransactions.
+type TransactionSource func(ctx context.Context, filter TransactionFilter) iter.Seq2[models.Transaction, error]
+
+// SplitWindows splits the date range of filter into at most n consecutive
+// windows of equal length, newest first. Since must be set; a zero Until
+// means now. Window bounds are whole seconds, as the API only accepts those,
+// and neighbouring windows share their bound.
+func SplitWindows(filter TransactionFilter, n int) ([]TransactionFilter, error) {
+	if filter.Since.IsZero() {
+		return nil, invalidFilterf("splitting transactions into date windows requires a since date")
+	}
+	if n < 1 {
+		return nil, invalidFilterf("invalid number of windows %d: must be at least 1", n)
+	}
+	until := filter.Until
+	if until.IsZero() {
+		until = time.Now().Truncate(time.Second)
+	}
+	if err := (TransactionFilter{Since: filter.Since, Until: until}).Validate(); err != nil {
+		return nil, err
+	}
+
+	// Windows shorter than a second would have the same bounds
+	span := until.Sub(filter.Since)
+	if limit := int(span / time.Second); n > limit {
+		n = max(limit, 1)
+	}
+	step := span / time.Duration(n)
+
+	windows := make([]TransactionFilter, n)
+	for i := range n {
+		w := filter
+		w.Until = until.Add(-time.Duration(i) * step).Truncate(time.Second)
+		w.Since = until.Add(-time.Duration(i+1) * step).Truncate(time.Second)
+		if i == 0 {
+			w.Until = until
+		}
+		if i == n-1 {
+			w.Since = filter.Since
+		}
+		windows[i] = w
+	}
+	return windows, nil
+}
+
+// window holds the outcome of fetching one date window.
+type window struct {
+	transactions []models.Transaction
+	err          error
+	done         chan struct{}
+}
+
+// ParallelTransactions fetches the transactions matching filter by splitting
+// its date range into the given number of windows and fetching up to workers
+// of them at once. Transactions are yielded newest first, as with a
+// sequential fetch, and each window is yielded as soon as it and every newer
+// window are complete. A transaction on the bound between two windows is
+// only yielded once. Iteration ends after the first error; stopping early
+// cancels the windows still being fetched.
+func ParallelTransactions(ctx context.Context, source TransactionSource, filter TransactionFilter, windows, workers int) iter.Seq2[models.Transaction, error] {
+	if err := filter.Validate(); err != nil {
+		return invalid[models.Transaction](err)
+	}
+	filters, err := SplitWindows(filter, windows)
+	if err != nil {
+		return invalid[models.Transaction](err)
+	}
+	workers = min(max(workers, 1), len(filters))
+
+	return func(yield func(models.Transaction, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		results := make([]*window, len(filters))
+		for i := range results {
+			results[i] = &window{done: make(chan struct{})}
+		}
+
+		// A fixed pool of workers takes windows newest first, so the windows
+		// needed first are fetched first
+		next := make(chan int)
+		var wg sync.WaitGroup
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					r := results[i]
+					r.transactions, r.err = collect(source(ctx, filters[i]))
+					if r.err != nil {
+						r.err = fmt.Errorf("fetching transactions from %s to %s: %w",
+							filters[i].Since.Format(time.RFC3339), filters[i].Until.Format(time.RFC3339), r.err)
+					}
+					close(r.done)
+				}
+			}()
+		}
+		go func() {
+			defer close(next)
+			for i := range filters {
+				select {
+				case next <- i:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		// Stop the workers before returning, whether or not iteration
+		// completed
+		defer wg.Wait()
+		defer cancel()
+
+		// Only a transaction in the same second as the bound shared with the
+		// previous window can have been fetched twice, so only those are
+		// remembered, and only until the next window
+		var edge map[string]bool
+		for i, r := range results {
+			select {
+			case <-r.done:
+			case <-ctx.Done():
+				yield(models.Transaction{}, ctx.Err())
+				return
+			}
+			if r.err != nil {
+				yield(models.Transaction{}, r.err)
+				return
+			}
+
+			sort.Stable(models.ByDate(r.transactions))
+			next := make(map[string]bool)
+			for _, tx := range r.transactions {
+				if edge[tx.ID] {
+					continue
+				}
+				if tx.Attributes.CreatedAt.Truncate(time.Second).Equal(filters[i].Since) {
+					next[tx.ID] = true
+				}
+				if !yield(tx, nil) {
+					return
+				}
+			}
+			edge = next
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"testing"
+	"time"
+	"upbank-cli/pkg/models"
+)
+
+// sliceSource lists the transactions between the bounds of a filter from a
+// slice. Both bounds are inclusive, so a transaction on the bound between two
+// windows is returned for both.
+func sliceSource(transactions []models.Transaction) TransactionSource {
+	return func(ctx context.Context, filter TransactionFilter) iter.Seq2[models.Transaction, error] {
+		return func(yield func(models.Transaction, error) bool) {
+			for _, tx := range transactions {
+				at := tx.Attributes.CreatedAt
+				if (!filter.Since.IsZero() && at.Before(filter.Since)) || (!filter.Until.IsZero() && at.After(filter.Until)) {
+					continue
+				}
+				if !yield(tx, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func transaction(id string, at time.Time) models.Transaction {
+	var tx models.Transaction
+	tx.ID = id
+	tx.Attributes.CreatedAt = at
+	return tx
+}
+
+func TestParallelTransactions(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(100 * time.Hour)
+	filter := TransactionFilter{Since: since, Until: until}
+
+	windows, err := SplitWindows(filter, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bound := windows[1].Since
+
+	var transactions []models.Transaction
+	for i := range 100 {
+		transactions = append(transactions, transaction(fmt.Sprintf("tx-%03d", i), since.Add(time.Duration(i)*time.Hour+time.Minute)))
+	}
+	// Exactly on a window bound, twice, and within the same second as one
+	transactions = append(transactions,
+		transaction("on-bound", bound),
+		transaction("on-bound-too", bound),
+		transaction("same-second", bound.Add(300*time.Millisecond)),
+	)
+	slices.SortStableFunc(transactions, func(a, b models.Transaction) int {
+		return b.Attributes.CreatedAt.Compare(a.Attributes.CreatedAt)
+	})
+
+	ctx := context.Background()
+	sequential, err := collect(sliceSource(transactions)(ctx, filter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{1, 3, 8} {
+		parallel, err := collect(ParallelTransactions(ctx, sliceSource(transactions), filter, 4, workers))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.EqualFunc(parallel, sequential, func(a, b models.Transaction) bool { return a.ID == b.ID }) {
+			t.Errorf("%d workers: got %d transactions, want the %d of a sequential fetch", workers, len(parallel), len(sequential))
+		}
+	}
+}
+
+func TestParallelTransactionsError(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	filter := TransactionFilter{Since: since, Until: since.Add(4 * time.Hour)}
+	failure := errors.New("boom")
+
+	source := func(ctx context.Context, f TransactionFilter) iter.Seq2[models.Transaction, error] {
+		if f.Since.Equal(since) {
+			return invalid[models.Transaction](failure)
+		}
+		return sliceSource(nil)(ctx, f)
+	}
+	_, err := collect(ParallelTransactions(context.Background(), source, filter, 4, 2))
+	if !errors.Is(err, failure) {
+		t.Errorf("error = %v, want the failure of the oldest window", err)
+	}
+
+	if _, err := collect(ParallelTransactions(context.Background(), source, TransactionFilter{}, 4, 2)); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("without since: error = %v, want ErrInvalidFilter", err)
+	}
+}
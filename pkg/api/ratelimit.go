@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithRateLimit caps how many requests per second the client sends. The
+// limit is shared by every goroutine using the client, so concurrent
+// fetches don't multiply the load on the API. Zero or negative means no
+// limit.
+func WithRateLimit(perSecond float64) Option {
+	return func(c *Client) {
+		c.limiter = newRateLimiter(perSecond)
+	}
+}
+
+// rateLimiter spaces requests evenly, handing out one slot per interval.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter returns a limiter for perSecond requests per second, or nil
+// for no limit.
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until the caller may send a request or ctx is done. A nil
+// limiter never blocks.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	if d := time.Until(slot); d > 0 {
+		return sleep(ctx, d)
+	}
+	return nil
+}
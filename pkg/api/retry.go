@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy controls how requests that fail with a rate limit or a
+// transient error are retried.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries for a single request after the
+	// first attempt. Zero disables retries.
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry. It doubles on every
+	// further retry, with full jitter applied.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff between two attempts. A Retry-After header
+	// sent by the server is honoured even when it exceeds MaxDelay.
+	MaxDelay time.Duration
+	// Budget is the total number of retries the client may spend across all
+	// requests. Zero or negative means unlimited.
+	Budget int
+}
+
+// DefaultRetryPolicy is used by clients created without WithRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+	Budget:     20,
+}
+
+// WithRetryPolicy sets the retry behaviour of the client.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// retryBudget tracks the retries spent by a client.
+type retryBudget struct {
+	used atomic.Int64
+}
+
+// take reserves one retry, reporting false once the budget is exhausted.
+func (b *retryBudget) take(limit int) bool {
+	if limit <= 0 {
+		return true
+	}
+	if b.used.Add(1) > int64(limit) {
+		b.used.Add(-1)
+		return false
+	}
+	return true
+}
+
+// shouldRetry reports whether a failed attempt is worth repeating.
+func shouldRetry(ctx context.Context, method string, err error) bool {
+	// The caller gave up; don't keep trying on their behalf
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		// A POST may have taken effect before a server error or a dropped
+		// connection, so only retry it when it was rejected outright
+		if method == http.MethodPost {
+			return apiErr.StatusCode == http.StatusTooManyRequests
+		}
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	// A cassette gives the same answer every time
+	if errors.Is(err, ErrNotRecorded) {
+		return false
+	}
+
+	// Connection failures and per-request timeouts are treated as transient.
+	// Decoding errors are not, as the same response would be returned again.
+	var urlErr *url.Error
+	return method != http.MethodPost && errors.As(err, &urlErr)
+}
+
+// backoff returns how long to wait before retry number n (starting at 1).
+func (p RetryPolicy) backoff(n int, err error) time.Duration {
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+
+	delay := p.BaseDelay << (n - 1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return rand.N(delay) + 1
+}
+
+// parseRetryAfter interprets a Retry-After header given either in seconds or
+// as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
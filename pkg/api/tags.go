@@ -0,0 +1,48 @@
+package api
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"upbank-cli/pkg/models"
+)
+
+// Tags returns an iterator over every tag in use, fetching pages lazily as
+// the caller consumes them. A pageSize of zero uses the API default.
+func (c *Client) Tags(ctx context.Context, pageSize int) iter.Seq2[models.Tag, error] {
+	if err := validatePageSize(pageSize); err != nil {
+		return invalid[models.Tag](err)
+	}
+	return paginate[models.Tag](ctx, c, "tags", c.buildURL("/tags", pageQuery(pageSize)))
+}
+
+// ListTags collects every tag in use across all pages.
+func (c *Client) ListTags(ctx context.Context) ([]models.Tag, error) {
+	return collect(c.Tags(ctx, 0))
+}
+
+// AddTags attaches tags to a transaction. Tags that don't exist yet are
+// created, and tags already on the transaction are left alone.
+func (c *Client) AddTags(ctx context.Context, transactionID string, tags ...string) error {
+	return c.updateTags(ctx, http.MethodPost, transactionID, tags)
+}
+
+// RemoveTags detaches tags from a transaction. Tags not on the transaction
+// are ignored.
+func (c *Client) RemoveTags(ctx context.Context, transactionID string, tags ...string) error {
+	return c.updateTags(ctx, http.MethodDelete, transactionID, tags)
+}
+
+// updateTags sends a tags relationship update for a transaction.
+func (c *Client) updateTags(ctx context.Context, method, transactionID string, tags []string) error {
+	var body struct {
+		Data []resourceIdentifier `json:"data"`
+	}
+	for _, tag := range tags {
+		body.Data = append(body.Data, resourceIdentifier{Type: "tags", ID: tag})
+	}
+
+	url := c.buildURL("/transactions/"+url.PathEscape(transactionID)+"/relationships/tags", nil)
+	return c.do(ctx, method, url, body, nil)
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// WithLogger traces every HTTP request to logger at debug level: the
+// method, the final URL, the page of a list, the status, the latency and the
+// response size. The Authorization header is always redacted.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithBodyDump saves the body of every API response to a numbered file in
+// dir, for debugging. Unlike a cassette, the bodies are not scrubbed.
+func WithBodyDump(dir string) Option {
+	return func(c *Client) {
+		c.dumpDir = dir
+	}
+}
+
+// pageKey is the context key holding the page number of a list request.
+type pageKey struct{}
+
+// withPage records in ctx that requests made with it fetch page n of a list.
+func withPage(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, pageKey{}, n)
+}
+
+// pageFrom returns the page number recorded by withPage, or 0.
+func pageFrom(ctx context.Context) int {
+	n, _ := ctx.Value(pageKey{}).(int)
+	return n
+}
+
+// tracer is a RoundTripper that logs requests and dumps response bodies.
+type tracer struct {
+	next    http.RoundTripper
+	logger  *slog.Logger
+	dumpDir string
+	// baseURL tells API requests apart from others, such as attachment
+	// downloads, whose pre-signed URLs must not be logged in full
+	baseURL string
+	seq     atomic.Int64
+}
+
+func (t *tracer) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	latency := time.Since(start)
+
+	isAPI := strings.HasPrefix(req.URL.String(), t.baseURL)
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", t.redactURL(req, isAPI)),
+	}
+	if page := pageFrom(req.Context()); page > 0 {
+		attrs = append(attrs, slog.Int("page", page))
+	}
+	attrs = append(attrs, slog.Any("headers", redactHeaders(req.Header)))
+
+	if err != nil {
+		attrs = append(attrs, slog.Duration("latency", latency), slog.String("error", err.Error()))
+		t.log(req.Context(), "http request failed", attrs)
+		return nil, err
+	}
+	attrs = append(attrs, slog.Int("status", resp.StatusCode), slog.Duration("latency", latency))
+
+	// Log once the body has been read, so the size is known even when the
+	// server doesn't send a Content-Length
+	body := &tracedBody{ReadCloser: resp.Body, drain: isAPI, done: func(size int64) {
+		t.log(req.Context(), "http request", append(attrs, slog.Int64("bytes", size)))
+	}}
+	if t.dumpDir != "" && isAPI {
+		if f, err := t.dumpFile(req, resp); err != nil {
+			t.log(req.Context(), "could not dump response body", []slog.Attr{slog.String("error", err.Error())})
+		} else {
+			body.dump = f
+		}
+	}
+	resp.Body = body
+	return resp, nil
+}
+
+// log writes a record if a logger is set.
+func (t *tracer) log(ctx context.Context, msg string, attrs []slog.Attr) {
+	if t.logger != nil {
+		t.logger.LogAttrs(ctx, slog.LevelDebug, msg, attrs...)
+	}
+}
+
+// redactURL returns the URL to log. The query of a request outside the API
+// is dropped, as it may carry a signature granting access to a file.
+func (t *tracer) redactURL(req *http.Request, isAPI bool) string {
+	if isAPI {
+		return req.URL.String()
+	}
+	return redactQuery(*req.URL)
+}
+
+// redactQuery returns u with its query, if any, replaced by REDACTED.
+func redactQuery(u url.URL) string {
+	if u.RawQuery != "" {
+		u.RawQuery = "REDACTED"
+	}
+	return u.String()
+}
+
+// redactHeaders returns the request headers with credentials replaced.
+func redactHeaders(h http.Header) map[string]string {
+	headers := make(map[string]string, len(h))
+	for name, values := range h {
+		if name == "Authorization" || name == "Cookie" {
+			headers[name] = "REDACTED"
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+	return headers
+}
+
+// dumpFile creates the file the body of resp is copied to, named after the
+// order of the request, its method and the response status.
+func (t *tracer) dumpFile(req *http.Request, resp *http.Response) (*os.File, error) {
+	if err := os.MkdirAll(t.dumpDir, 0o700); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%04d-%s-%d.json", t.seq.Add(1), strings.ToLower(req.Method), resp.StatusCode)
+	return os.OpenFile(filepath.Join(t.dumpDir, name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+}
+
+// tracedBody counts the bytes read from a response body, copying them to
+// dump if set, and reports the total once the body is closed.
+type tracedBody struct {
+	io.ReadCloser
+	dump  *os.File
+	drain bool
+	size  int64
+	done  func(size int64)
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if b.dump != nil && n > 0 {
+		_, _ = b.dump.Write(p[:n])
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	// The client closes API bodies it decoded without reading to EOF, so
+	// drain what's left to report the full size and dump the whole body
+	if b.drain {
+		_, _ = io.Copy(io.Discard, b)
+	}
+	if b.dump != nil {
+		_ = b.dump.Close()
+	}
+	if b.done != nil {
+		b.done(b.size)
+		b.done = nil
+	}
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// WithBaseURL points the client at a different API root, such as a local
+// stand-in server or a recording proxy. It should include the version path,
+// e.g. http://localhost:8080/api/v1.
+func WithBaseURL(u string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(u, "/")
+	}
+}
+
+// WithTransport sets the RoundTripper used for every request. It cannot be
+// combined with WithCABundle or WithProxy, which configure the default
+// transport.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.transport = rt
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithCABundle trusts the PEM encoded certificates in the file at path in
+// addition to the system roots, e.g. for a corporate TLS-intercepting proxy.
+func WithCABundle(path string) Option {
+	return func(c *Client) {
+		c.caBundle = path
+	}
+}
+
+// WithProxy sends every request through the proxy at proxyURL instead of the
+// one configured by the HTTP_PROXY/HTTPS_PROXY environment variables.
+func WithProxy(proxyURL string) Option {
+	return func(c *Client) {
+		c.proxyURL = proxyURL
+	}
+}
+
+// buildTransport returns the RoundTripper for the client, wrapped by a
+// cassette recorder or replaced by a replayer if one was requested, and
+// traced if a logger or body dump was requested.
+func (c *Client) buildTransport() (http.RoundTripper, error) {
+	t, err := c.cassetteTransport()
+	if err != nil {
+		return nil, err
+	}
+	if c.logger != nil || c.dumpDir != "" {
+		return &tracer{next: t, logger: c.logger, dumpDir: c.dumpDir, baseURL: c.baseURL}, nil
+	}
+	return t, nil
+}
+
+// cassetteTransport returns the base transport, wrapped by a cassette
+// recorder or replaced by a replayer if one was requested.
+func (c *Client) cassetteTransport() (http.RoundTripper, error) {
+	if c.recordDir != "" && c.replayDir != "" {
+		return nil, fmt.Errorf("a cassette cannot be recorded and replayed at the same time")
+	}
+	if c.replayDir != "" {
+		r, err := NewReplayer(c.replayDir)
+		if err != nil {
+			return nil, err
+		}
+		r.BaseURL = c.baseURL
+		return r, nil
+	}
+
+	t, err := c.baseTransport()
+	if err != nil {
+		return nil, err
+	}
+	if c.recordDir != "" {
+		return &Recorder{Dir: c.recordDir, Next: t, BaseURL: c.baseURL}, nil
+	}
+	return t, nil
+}
+
+// baseTransport returns the RoundTripper that talks to the API, applying the
+// CA bundle and proxy settings to a copy of the default transport.
+func (c *Client) baseTransport() (http.RoundTripper, error) {
+	if c.transport != nil {
+		if c.caBundle != "" || c.proxyURL != "" {
+			return nil, fmt.Errorf("a custom transport cannot be combined with a CA bundle or proxy")
+		}
+		return c.transport, nil
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if c.caBundle != "" {
+		pem, err := os.ReadFile(c.caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA bundle: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", c.caBundle)
+		}
+		t.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	if c.proxyURL != "" {
+		proxy, err := url.Parse(c.proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %v", err)
+		}
+		t.Proxy = http.ProxyURL(proxy)
+	}
+
+	return t, nil
+}
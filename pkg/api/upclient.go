@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"io"
+	"iter"
+	"upbank-cli/pkg/models"
+)
+
+// UpClient is every Up API operation, as implemented by Client. Code that
+// only needs to talk to Up should depend on it rather than on *Client, so
+// that another backend can be substituted: a fake server, a cached or
+// offline copy, or a client fanning out over several profiles.
+type UpClient interface {
+	// BaseURL identifies the backend, e.g. to key caches by it
+	BaseURL() string
+	Ping(ctx context.Context) (PingResult, error)
+
+	Accounts(ctx context.Context, filter AccountFilter) iter.Seq2[models.Account, error]
+	GetAccounts(ctx context.Context, filter AccountFilter) ([]models.Account, error)
+	GetAccount(ctx context.Context, id string) (models.Account, error)
+
+	Transactions(ctx context.Context, filter TransactionFilter) iter.Seq2[models.Transaction, error]
+	AccountTransactions(ctx context.Context, accountID string, filter TransactionFilter) iter.Seq2[models.Transaction, error]
+	GetTransactions(ctx context.Context, filter TransactionFilter) ([]models.Transaction, error)
+	GetAccountTransactions(ctx context.Context, accountID string, filter TransactionFilter) ([]models.Transaction, error)
+	GetTransaction(ctx context.Context, id string) (models.Transaction, error)
+
+	ListCategories(ctx context.Context, parent string) ([]models.Category, error)
+	GetCategory(ctx context.Context, id string) (models.Category, error)
+	Categorize(ctx context.Context, transactionID, categoryID string) error
+
+	Tags(ctx context.Context, pageSize int) iter.Seq2[models.Tag, error]
+	ListTags(ctx context.Context) ([]models.Tag, error)
+	AddTags(ctx context.Context, transactionID string, tags ...string) error
+	RemoveTags(ctx context.Context, transactionID string, tags ...string) error
+
+	Attachments(ctx context.Context, pageSize int) iter.Seq2[models.Attachment, error]
+	ListAttachments(ctx context.Context) ([]models.Attachment, error)
+	GetAttachment(ctx context.Context, id string) (models.Attachment, error)
+	DownloadAttachment(ctx context.Context, attachment models.Attachment, w io.Writer) (int64, error)
+
+	CreateWebhook(ctx context.Context, webhookURL, description string) (models.Webhook, error)
+	Webhooks(ctx context.Context, pageSize int) iter.Seq2[models.Webhook, error]
+	ListWebhooks(ctx context.Context) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	PingWebhook(ctx context.Context, id string) (models.WebhookEvent, error)
+	WebhookLogs(ctx context.Context, id string, pageSize int) iter.Seq2[models.WebhookDeliveryLog, error]
+	ListWebhookLogs(ctx context.Context, id string) ([]models.WebhookDeliveryLog, error)
+}
+
+var _ UpClient = (*Client)(nil)
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// PingResult describes a successful call to the ping endpoint.
+type PingResult struct {
+	// ID is the unique ID of the request, as reported by Up
+	ID          string
+	StatusEmoji string
+	// ServerTime is the Date header of the response; zero if absent
+	ServerTime time.Time
+	// Latency is the time from sending the request to receiving the response
+	Latency time.Duration
+}
+
+// Ping checks that the API is reachable and the token is accepted. Unlike
+// other requests it is never retried, so that it reports the outcome of a
+// single attempt.
+func (c *Client) Ping(ctx context.Context) (PingResult, error) {
+	if c.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.requestTimeout)
+		defer cancel()
+	}
+
+	pingURL := c.buildURL("/util/ping", nil)
+	req, err := c.newRequest(ctx, http.MethodGet, pingURL, nil)
+	if err != nil {
+		return PingResult{}, err
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return PingResult{}, fmt.Errorf("error making request: %w", err)
+	}
+	defer c.closeBody(ctx, pingURL, resp)
+
+	result := PingResult{Latency: time.Since(start)}
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		result.ServerTime = date
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, newError(resp)
+	}
+
+	var response struct {
+		Meta struct {
+			ID          string `json:"id"`
+			StatusEmoji string `json:"statusEmoji"`
+		} `json:"meta"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return result, fmt.Errorf("error decoding response: %w", err)
+	}
+	result.ID = response.Meta.ID
+	result.StatusEmoji = response.Meta.StatusEmoji
+	return result, nil
+}
+
+// Proxy returns the proxy requests to the API go through, from WithProxy or
+// the HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment variables, or nil when
+// they are sent directly. It is nil as well with a custom transport or when
+// replaying a cassette.
+func (c *Client) Proxy() (*url.URL, error) {
+	rt := c.httpClient.Transport
+	if t, ok := rt.(*tracer); ok {
+		rt = t.next
+	}
+	if r, ok := rt.(*Recorder); ok {
+		rt = r.Next
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok || t.Proxy == nil {
+		return nil, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, c.baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return t.Proxy(req)
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"upbank-cli/pkg/models"
+)
+
+// CreateWebhook registers url to receive events. The returned webhook
+// carries the secret key used to sign deliveries; Up never returns it again.
+func (c *Client) CreateWebhook(ctx context.Context, webhookURL, description string) (models.Webhook, error) {
+	var body struct {
+		Data struct {
+			Attributes struct {
+				URL         string `json:"url"`
+				Description string `json:"description,omitempty"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	body.Data.Attributes.URL = webhookURL
+	body.Data.Attributes.Description = description
+
+	var response models.WebhookResponse
+	if err := c.do(ctx, http.MethodPost, c.buildURL("/webhooks", nil), body, &response); err != nil {
+		return models.Webhook{}, err
+	}
+	return response.Data, nil
+}
+
+// Webhooks returns an iterator over every registered webhook, fetching pages
+// lazily as the caller consumes them. A pageSize of zero uses the API
+// default.
+func (c *Client) Webhooks(ctx context.Context, pageSize int) iter.Seq2[models.Webhook, error] {
+	if err := validatePageSize(pageSize); err != nil {
+		return invalid[models.Webhook](err)
+	}
+	return paginate[models.Webhook](ctx, c, "webhooks", c.buildURL("/webhooks", pageQuery(pageSize)))
+}
+
+// ListWebhooks collects every registered webhook across all pages.
+func (c *Client) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	return collect(c.Webhooks(ctx, 0))
+}
+
+// GetWebhook retrieves a single webhook by ID.
+func (c *Client) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	var response models.WebhookResponse
+	if err := c.get(ctx, c.buildURL("/webhooks/"+url.PathEscape(id), nil), &response); err != nil {
+		return models.Webhook{}, err
+	}
+	return response.Data, nil
+}
+
+// DeleteWebhook deletes a webhook so it no longer receives events.
+func (c *Client) DeleteWebhook(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, c.buildURL("/webhooks/"+url.PathEscape(id), nil), nil, nil)
+}
+
+// PingWebhook sends a PING event to a webhook and returns the event.
+func (c *Client) PingWebhook(ctx context.Context, id string) (models.WebhookEvent, error) {
+	var response models.WebhookEventResponse
+	if err := c.do(ctx, http.MethodPost, c.buildURL("/webhooks/"+url.PathEscape(id)+"/ping", nil), nil, &response); err != nil {
+		return models.WebhookEvent{}, err
+	}
+	return response.Data, nil
+}
+
+// WebhookLogs returns an iterator over the delivery logs of a webhook,
+// newest first, fetching pages lazily as the caller consumes them.
+func (c *Client) WebhookLogs(ctx context.Context, id string, pageSize int) iter.Seq2[models.WebhookDeliveryLog, error] {
+	if err := validatePageSize(pageSize); err != nil {
+		return invalid[models.WebhookDeliveryLog](err)
+	}
+	return paginate[models.WebhookDeliveryLog](ctx, c, "webhook logs", c.buildURL("/webhooks/"+url.PathEscape(id)+"/logs", pageQuery(pageSize)))
+}
+
+// ListWebhookLogs collects every delivery log of a webhook across all pages.
+func (c *Client) ListWebhookLogs(ctx context.Context, id string) ([]models.WebhookDeliveryLog, error) {
+	return collect(c.WebhookLogs(ctx, id, 0))
+}
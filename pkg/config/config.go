@@ -0,0 +1,86 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// File holds the top-level keys of the JSON config file. Keys matching a
+// command-line flag name supply that flag's default; other keys hold
+// structured settings decoded with Decode.
+type File map[string]json.RawMessage
+
+// DefaultPath returns the location of the config file, honouring the
+// UPBANK_CONFIG environment variable.
+func DefaultPath() (string, error) {
+	if path := os.Getenv("UPBANK_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "upbank-cli", "config.json"), nil
+}
+
+// Load reads the config file at path. A missing file is not an error and
+// yields an empty config.
+func Load(path string) (File, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return File{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %v", err)
+	}
+
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %v", path, err)
+	}
+	return f, nil
+}
+
+// ApplyFlags sets every flag in fs that was not given on the command line
+// from the config key of the same name. Keys without a matching flag are
+// left alone.
+func (f File) ApplyFlags(fs *pflag.FlagSet) error {
+	var errs []error
+	fs.VisitAll(func(flag *pflag.Flag) {
+		raw, ok := f[flag.Name]
+		if !ok || flag.Changed {
+			return
+		}
+
+		// Strings are unquoted; numbers and booleans are used as written
+		value := strings.TrimSpace(string(raw))
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			value = s
+		}
+
+		if err := flag.Value.Set(value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid config value for %q: %v", flag.Name, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// Decode unmarshals the structured setting stored under key into v. It
+// reports false when the key is absent.
+func (f File) Decode(key string, v any) (bool, error) {
+	raw, ok := f[key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return true, fmt.Errorf("invalid config value for %q: %v", key, err)
+	}
+	return true, nil
+}
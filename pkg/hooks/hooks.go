@@ -0,0 +1,295 @@
+// Package hooks runs user commands in response to webhook events. Hooks are
+// declared in the config file, matched against each event and its
+// transaction, and their command line and stdin are rendered from Go
+// templates over the transaction.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"upbank-cli/pkg/models"
+)
+
+// DefaultTimeout is how long a hook may run when it sets no timeout.
+const DefaultTimeout = 30 * time.Second
+
+// maxOutput bounds how much of a failed hook's output is logged.
+const maxOutput = 4 << 10
+
+// Hook is a command run for matching events, as declared in the config file.
+// Empty filters match everything.
+type Hook struct {
+	Name string `json:"name"`
+	// Events lists the event types to run for. Defaults to
+	// TRANSACTION_CREATED and TRANSACTION_SETTLED.
+	Events []string `json:"events"`
+	// Account is the ID of the account the transaction must belong to.
+	Account string `json:"account"`
+	// MinAmount is compared against the absolute transaction amount, so it
+	// matches both spending and income.
+	MinAmount *float64 `json:"minAmount"`
+	// Category matches the transaction's category or parent category ID.
+	Category string `json:"category"`
+	// Description is a regular expression matched against the description.
+	Description string `json:"description"`
+	// Command is the program and its arguments. Each element is a template.
+	Command []string `json:"command"`
+	// Stdin is a template whose output is written to the command's stdin.
+	Stdin   string `json:"stdin"`
+	Timeout string `json:"timeout"`
+
+	description *regexp.Regexp
+	command     []*template.Template
+	stdin       *template.Template
+	timeout     time.Duration
+}
+
+// compile validates a hook and prepares its templates and expressions.
+func (h *Hook) compile() error {
+	if h.Name == "" {
+		return errors.New("hook has no name")
+	}
+	if len(h.Command) == 0 {
+		return fmt.Errorf("hook %q has no command", h.Name)
+	}
+
+	if len(h.Events) == 0 {
+		h.Events = []string{models.EventTransactionCreated, models.EventTransactionSettled}
+	}
+	for i, event := range h.Events {
+		event = strings.ToUpper(event)
+		switch event {
+		case models.EventTransactionCreated, models.EventTransactionSettled, models.EventTransactionDeleted, models.EventPing:
+		default:
+			return fmt.Errorf("hook %q: unknown event type %q", h.Name, event)
+		}
+		h.Events[i] = event
+	}
+
+	if h.Description != "" {
+		re, err := regexp.Compile(h.Description)
+		if err != nil {
+			return fmt.Errorf("hook %q: invalid description pattern: %v", h.Name, err)
+		}
+		h.description = re
+	}
+
+	for i, arg := range h.Command {
+		t, err := parseTemplate(fmt.Sprintf("%s.command[%d]", h.Name, i), arg)
+		if err != nil {
+			return fmt.Errorf("hook %q: %v", h.Name, err)
+		}
+		h.command = append(h.command, t)
+	}
+	if h.Stdin != "" {
+		t, err := parseTemplate(h.Name+".stdin", h.Stdin)
+		if err != nil {
+			return fmt.Errorf("hook %q: %v", h.Name, err)
+		}
+		h.stdin = t
+	}
+
+	h.timeout = DefaultTimeout
+	if h.Timeout != "" {
+		d, err := time.ParseDuration(h.Timeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("hook %q: invalid timeout %q", h.Name, h.Timeout)
+		}
+		h.timeout = d
+	}
+	return nil
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+// Matches reports whether the hook runs for an event about tx.
+func (h *Hook) Matches(eventType string, tx models.Transaction) bool {
+	if !slices.Contains(h.Events, eventType) {
+		return false
+	}
+	if h.Account != "" && tx.Relations.Account.Data.ID != h.Account {
+		return false
+	}
+	if h.Category != "" && !inCategory(tx, h.Category) {
+		return false
+	}
+	if h.MinAmount != nil {
+		amount, err := strconv.ParseFloat(tx.Attributes.Amount.Value, 64)
+		if err != nil || math.Abs(amount) < *h.MinAmount {
+			return false
+		}
+	}
+	if h.description != nil && !h.description.MatchString(tx.Attributes.Description) {
+		return false
+	}
+	return true
+}
+
+// inCategory reports whether tx is in the category or its parent is.
+func inCategory(tx models.Transaction, category string) bool {
+	if c := tx.Relations.Category.Data; c != nil && c.ID == category {
+		return true
+	}
+	if p := tx.Relations.ParentCategory.Data; p != nil && p.ID == category {
+		return true
+	}
+	return false
+}
+
+// render executes a template over the transaction.
+func render(t *template.Template, tx models.Transaction) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, tx); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Runner runs hooks in the background, at most a fixed number at a time.
+type Runner struct {
+	hooks []*Hook
+	sem   chan struct{}
+	log   io.Writer
+	logMu sync.Mutex
+	wg    sync.WaitGroup
+}
+
+// NewRunner validates hooks and returns a Runner that runs at most
+// concurrency of them at once and reports their outcome to log.
+func NewRunner(hooks []Hook, concurrency int, log io.Writer) (*Runner, error) {
+	if concurrency < 1 {
+		return nil, fmt.Errorf("hook concurrency must be at least 1, got %d", concurrency)
+	}
+
+	r := &Runner{sem: make(chan struct{}, concurrency), log: log}
+	names := make(map[string]bool)
+	for i := range hooks {
+		h := hooks[i]
+		if err := h.compile(); err != nil {
+			return nil, err
+		}
+		if names[h.Name] {
+			return nil, fmt.Errorf("duplicate hook name %q", h.Name)
+		}
+		names[h.Name] = true
+		r.hooks = append(r.hooks, &h)
+	}
+	return r, nil
+}
+
+// Len returns the number of hooks.
+func (r *Runner) Len() int {
+	return len(r.hooks)
+}
+
+// Dispatch starts every hook matching the event in the background and
+// returns how many were started. Hooks keep running after ctx is done
+// until their own timeout, so that a delivery being answered does not kill
+// them; use Wait to let them finish.
+func (r *Runner) Dispatch(ctx context.Context, event models.WebhookEvent, tx models.Transaction) int {
+	var started int
+	for _, h := range r.hooks {
+		if !h.Matches(event.Attributes.EventType, tx) {
+			continue
+		}
+		started++
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.sem <- struct{}{}
+			defer func() { <-r.sem }()
+
+			start := time.Now()
+			if err := r.run(context.WithoutCancel(ctx), h, event, tx); err != nil {
+				r.logf("Hook %q failed for event %s (transaction %s) after %s: %v\n", h.Name, event.ID, tx.ID, time.Since(start).Round(time.Millisecond), err)
+				return
+			}
+			r.logf("Hook %q ran for event %s (transaction %s) in %s\n", h.Name, event.ID, tx.ID, time.Since(start).Round(time.Millisecond))
+		}()
+	}
+	return started
+}
+
+// Wait blocks until every dispatched hook has finished.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+func (r *Runner) logf(format string, args ...any) {
+	r.logMu.Lock()
+	defer r.logMu.Unlock()
+	fmt.Fprintf(r.log, format, args...)
+}
+
+// run renders and executes a single hook. The event is also passed in the
+// UPBANK_EVENT_TYPE, UPBANK_EVENT_ID and UPBANK_TRANSACTION_ID environment
+// variables.
+func (r *Runner) run(ctx context.Context, h *Hook, event models.WebhookEvent, tx models.Transaction) error {
+	args := make([]string, len(h.command))
+	for i, t := range h.command {
+		arg, err := render(t, tx)
+		if err != nil {
+			return fmt.Errorf("error rendering command: %v", err)
+		}
+		args[i] = arg
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.WaitDelay = time.Second
+	cmd.Env = append(os.Environ(),
+		"UPBANK_EVENT_TYPE="+event.Attributes.EventType,
+		"UPBANK_EVENT_ID="+event.ID,
+		"UPBANK_TRANSACTION_ID="+tx.ID,
+	)
+	if h.stdin != nil {
+		stdin, err := render(h.stdin, tx)
+		if err != nil {
+			return fmt.Errorf("error rendering stdin: %v", err)
+		}
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	output := &limitedBuffer{max: maxOutput}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", h.timeout)
+	}
+	if err != nil && output.Len() > 0 {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(output.String()))
+	}
+	return err
+}
+
+// limitedBuffer keeps the first max bytes written to it and discards the
+// rest.
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
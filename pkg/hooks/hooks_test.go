@@ -0,0 +1,172 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"upbank-cli/pkg/models"
+)
+
+// testTransaction is a $12.50 coffee from the spending account, in the
+// restaurants-and-cafes category under good-life.
+func testTransaction(t *testing.T) models.Transaction {
+	t.Helper()
+	const body = `{
+		"type": "transactions",
+		"id": "tx-1",
+		"attributes": {
+			"description": "Coffee Shop",
+			"amount": {"currencyCode": "AUD", "value": "-12.50", "valueInBaseUnits": -1250}
+		},
+		"relationships": {
+			"account": {"data": {"type": "accounts", "id": "acc-spending"}},
+			"category": {"data": {"type": "categories", "id": "restaurants-and-cafes"}},
+			"parentCategory": {"data": {"type": "categories", "id": "good-life"}}
+		}
+	}`
+	var tx models.Transaction
+	if err := json.Unmarshal([]byte(body), &tx); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestMatches(t *testing.T) {
+	tx := testTransaction(t)
+	uncategorized := testTransaction(t)
+	uncategorized.Relations.Category.Data = nil
+	uncategorized.Relations.ParentCategory.Data = nil
+	amount := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		name  string
+		hook  Hook
+		event string
+		tx    models.Transaction
+		want  bool
+	}{
+		{"no filters", Hook{}, models.EventTransactionCreated, tx, true},
+		{"default events exclude deleted", Hook{}, models.EventTransactionDeleted, tx, false},
+		{"listed event", Hook{Events: []string{"transaction_deleted"}}, models.EventTransactionDeleted, tx, true},
+		{"account", Hook{Account: "acc-spending"}, models.EventTransactionCreated, tx, true},
+		{"other account", Hook{Account: "acc-savings"}, models.EventTransactionCreated, tx, false},
+		{"category", Hook{Category: "restaurants-and-cafes"}, models.EventTransactionCreated, tx, true},
+		{"parent category", Hook{Category: "good-life"}, models.EventTransactionCreated, tx, true},
+		{"other category", Hook{Category: "groceries"}, models.EventTransactionCreated, tx, false},
+		{"uncategorized", Hook{Category: "good-life"}, models.EventTransactionCreated, uncategorized, false},
+		{"amount above minimum", Hook{MinAmount: amount(10)}, models.EventTransactionCreated, tx, true},
+		{"amount equal to minimum", Hook{MinAmount: amount(12.5)}, models.EventTransactionCreated, tx, true},
+		{"amount below minimum", Hook{MinAmount: amount(20)}, models.EventTransactionCreated, tx, false},
+		{"description", Hook{Description: "(?i)coffee"}, models.EventTransactionCreated, tx, true},
+		{"other description", Hook{Description: "^Tea"}, models.EventTransactionCreated, tx, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := tt.hook
+			h.Name = "test"
+			h.Command = []string{"true"}
+			if err := h.compile(); err != nil {
+				t.Fatal(err)
+			}
+			if got := h.Matches(tt.event, tt.tx); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		hook Hook
+		want string
+	}{
+		{"no name", Hook{Command: []string{"true"}}, "no name"},
+		{"no command", Hook{Name: "h"}, "no command"},
+		{"unknown event", Hook{Name: "h", Command: []string{"true"}, Events: []string{"NOPE"}}, "unknown event type"},
+		{"bad pattern", Hook{Name: "h", Command: []string{"true"}, Description: "("}, "invalid description pattern"},
+		{"bad template", Hook{Name: "h", Command: []string{"{{.ID"}}, "command[0]"},
+		{"bad timeout", Hook{Name: "h", Command: []string{"true"}, Timeout: "-1s"}, "invalid timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.hook.compile()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("compile error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunner(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run hooks with")
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	hooks := []Hook{
+		{
+			Name:    "record",
+			Command: []string{"sh", "-c", `printf '%s|%s|%s|%s' "$1" "$2" "$UPBANK_EVENT_ID" "$(cat)" > "$0"`, out, "{{.ID}}", "{{.Attributes.Description}}"},
+			Stdin:   "{{.Attributes.Amount.Value}}",
+		},
+		{Name: "fail", Command: []string{"sh", "-c", "echo oops; exit 3"}},
+		{Name: "slow", Command: []string{"sleep", "5"}, Timeout: "100ms"},
+		{Name: "missing key", Command: []string{"echo", "{{.NoSuchField}}"}},
+		{Name: "other account", Account: "acc-savings", Command: []string{"true"}},
+	}
+	var log bytes.Buffer
+	r, err := NewRunner(hooks, 2, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Len() != len(hooks) {
+		t.Errorf("Len = %d, want %d", r.Len(), len(hooks))
+	}
+
+	var event models.WebhookEvent
+	event.ID = "evt-1"
+	event.Attributes.EventType = models.EventTransactionCreated
+	if n := r.Dispatch(context.Background(), event, testTransaction(t)); n != 4 {
+		t.Errorf("Dispatch started %d hooks, want 4", n)
+	}
+	r.Wait()
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("record hook didn't run: %v\n%s", err, log.String())
+	}
+	if want := "tx-1|Coffee Shop|evt-1|-12.50"; string(got) != want {
+		t.Errorf("record hook got %q, want %q", got, want)
+	}
+	for _, want := range []string{
+		`Hook "record" ran for event evt-1 (transaction tx-1)`,
+		`Hook "fail" failed for event evt-1 (transaction tx-1) after`,
+		"exit status 3: oops",
+		`Hook "slow" failed`,
+		"timed out after 100ms",
+		`Hook "missing key" failed`,
+		"error rendering command",
+	} {
+		if !strings.Contains(log.String(), want) {
+			t.Errorf("log is missing %q:\n%s", want, log.String())
+		}
+	}
+	if strings.Contains(log.String(), "other account") {
+		t.Errorf("non-matching hook ran:\n%s", log.String())
+	}
+}
+
+func TestNewRunnerErrors(t *testing.T) {
+	if _, err := NewRunner(nil, 0, &bytes.Buffer{}); err == nil {
+		t.Error("concurrency 0 accepted")
+	}
+	dup := []Hook{{Name: "h", Command: []string{"true"}}, {Name: "h", Command: []string{"true"}}}
+	if _, err := NewRunner(dup, 1, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("duplicate names: error = %v", err)
+	}
+}
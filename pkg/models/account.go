@@ -35,9 +35,9 @@ type AccountRelations struct {
 	Transactions TransactionLinks `json:"transactions"`
 }
 
-// AccountsResponse represents the API response for accounts
-type AccountsResponse struct {
-	Data []Account `json:"data"`
+// AccountResponse represents the API response for a single account
+type AccountResponse struct {
+	Data Account `json:"data"`
 }
 
 // ByTypeAndName implements sort.Interface for []Account based on
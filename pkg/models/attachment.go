@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Attachment represents a file, such as a receipt, attached to a transaction
+type Attachment struct {
+	Type       string          `json:"type"`
+	ID         string          `json:"id"`
+	Attributes AttachmentAttr  `json:"attributes"`
+	Relations  AttachmentRel   `json:"relationships"`
+	Links      AttachmentLinks `json:"links"`
+}
+
+// AttachmentAttr represents the attributes of an attachment
+type AttachmentAttr struct {
+	CreatedAt *time.Time `json:"createdAt"`
+	// FileURL is a pre-signed URL that stops working at FileURLExpiresAt
+	FileURL          *string   `json:"fileURL"`
+	FileURLExpiresAt time.Time `json:"fileURLExpiresAt"`
+	FileExtension    *string   `json:"fileExtension"`
+	FileContentType  *string   `json:"fileContentType"`
+}
+
+// AttachmentRel represents the relationships of an attachment
+type AttachmentRel struct {
+	Transaction struct {
+		Data struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"data"`
+		Links struct {
+			Related string `json:"related"`
+		} `json:"links"`
+	} `json:"transaction"`
+}
+
+// AttachmentLinks represents the links associated with an attachment
+type AttachmentLinks struct {
+	Self string `json:"self"`
+}
+
+// AttachmentResponse represents the API response for a single attachment
+type AttachmentResponse struct {
+	Data Attachment `json:"data"`
+}
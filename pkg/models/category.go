@@ -0,0 +1,55 @@
+package models
+
+// Category represents an Upbank transaction category
+type Category struct {
+	Type       string        `json:"type"`
+	ID         string        `json:"id"`
+	Attributes CategoryAttr  `json:"attributes"`
+	Relations  CategoryRel   `json:"relationships"`
+	Links      CategoryLinks `json:"links"`
+}
+
+// CategoryAttr represents the attributes of a category
+type CategoryAttr struct {
+	Name string `json:"name"`
+}
+
+// CategoryRel represents the relationships of a category
+type CategoryRel struct {
+	Parent struct {
+		Data *struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"data"`
+	} `json:"parent"`
+	Children struct {
+		Data []struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"data"`
+	} `json:"children"`
+}
+
+// CategoryLinks represents the links associated with a category
+type CategoryLinks struct {
+	Self string `json:"self"`
+}
+
+// CategoriesResponse represents the API response for categories
+type CategoriesResponse struct {
+	Data []Category `json:"data"`
+}
+
+// CategoryResponse represents the API response for a single category
+type CategoryResponse struct {
+	Data Category `json:"data"`
+}
+
+// ParentID returns the ID of the parent category, or "" for a parent
+// category.
+func (c Category) ParentID() string {
+	if c.Relations.Parent.Data == nil {
+		return ""
+	}
+	return c.Relations.Parent.Data.ID
+}
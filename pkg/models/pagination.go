@@ -0,0 +1,7 @@
+package models
+
+// PageLinks represents the links to neighbouring pages of a list response
+type PageLinks struct {
+	Prev *string `json:"prev"`
+	Next *string `json:"next"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// Tag represents a label that can be attached to transactions. Tags have no
+// attributes; the ID is the label itself.
+type Tag struct {
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	Relations struct {
+		Transactions struct {
+			Links struct {
+				Related string `json:"related"`
+			} `json:"links"`
+		} `json:"transactions"`
+	} `json:"relationships"`
+}
@@ -127,13 +127,9 @@ type TransactionLinks struct {
 	Self string `json:"self"`
 }
 
-// TransactionsResponse represents the API response for transactions
-type TransactionsResponse struct {
-	Data  []Transaction `json:"data"`
-	Links struct {
-		Prev *string `json:"prev"`
-		Next *string `json:"next"`
-	} `json:"links"`
+// TransactionResponse represents the API response for a single transaction
+type TransactionResponse struct {
+	Data Transaction `json:"data"`
 }
 
 // ByDate sorts transactions by date (newest first)
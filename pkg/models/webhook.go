@@ -0,0 +1,121 @@
+package models
+
+import "time"
+
+// Webhook event types sent by Up
+const (
+	EventTransactionCreated = "TRANSACTION_CREATED"
+	EventTransactionSettled = "TRANSACTION_SETTLED"
+	EventTransactionDeleted = "TRANSACTION_DELETED"
+	EventPing               = "PING"
+)
+
+// Webhook represents a webhook registered to receive events
+type Webhook struct {
+	Type       string       `json:"type"`
+	ID         string       `json:"id"`
+	Attributes WebhookAttr  `json:"attributes"`
+	Relations  WebhookRel   `json:"relationships"`
+	Links      WebhookLinks `json:"links"`
+}
+
+// WebhookAttr represents the attributes of a webhook
+type WebhookAttr struct {
+	URL         string  `json:"url"`
+	Description *string `json:"description"`
+	// SecretKey is only returned when the webhook is created
+	SecretKey *string   `json:"secretKey"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookRel represents the relationships of a webhook
+type WebhookRel struct {
+	Logs struct {
+		Links struct {
+			Related string `json:"related"`
+		} `json:"links"`
+	} `json:"logs"`
+}
+
+// WebhookLinks represents the links associated with a webhook
+type WebhookLinks struct {
+	Self string `json:"self"`
+}
+
+// WebhookResponse represents the API response for a single webhook
+type WebhookResponse struct {
+	Data Webhook `json:"data"`
+}
+
+// WebhookEvent represents an event delivered to a webhook
+type WebhookEvent struct {
+	Type       string           `json:"type"`
+	ID         string           `json:"id"`
+	Attributes WebhookEventAttr `json:"attributes"`
+	Relations  WebhookEventRel  `json:"relationships"`
+}
+
+// WebhookEventAttr represents the attributes of a webhook event
+type WebhookEventAttr struct {
+	EventType string    `json:"eventType"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookEventRel represents the relationships of a webhook event
+type WebhookEventRel struct {
+	Webhook struct {
+		Data struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"data"`
+		Links struct {
+			Related string `json:"related"`
+		} `json:"links"`
+	} `json:"webhook"`
+	// Transaction is only present for transaction events
+	Transaction *struct {
+		Data struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"data"`
+		Links struct {
+			Related string `json:"related"`
+		} `json:"links"`
+	} `json:"transaction,omitempty"`
+}
+
+// WebhookEventResponse represents the payload of a webhook event, both as
+// returned by the ping endpoint and as delivered to a webhook URL
+type WebhookEventResponse struct {
+	Data WebhookEvent `json:"data"`
+}
+
+// WebhookDeliveryLog represents an attempt to deliver an event to a webhook
+type WebhookDeliveryLog struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id"`
+	Attributes WebhookDeliveryLogAttr `json:"attributes"`
+	Relations  struct {
+		WebhookEvent struct {
+			Data struct {
+				Type string `json:"type"`
+				ID   string `json:"id"`
+			} `json:"data"`
+		} `json:"webhookEvent"`
+	} `json:"relationships"`
+}
+
+// WebhookDeliveryLogAttr represents the attributes of a delivery log
+type WebhookDeliveryLogAttr struct {
+	Request struct {
+		Body string `json:"body"`
+	} `json:"request"`
+	// Response is nil when no response was received
+	Response *struct {
+		StatusCode int    `json:"statusCode"`
+		Body       string `json:"body"`
+	} `json:"response"`
+	// DeliveryStatus is DELIVERED, UNDELIVERABLE or BAD_RESPONSE_CODE
+	DeliveryStatus string    `json:"deliveryStatus"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
@@ -0,0 +1,124 @@
+package uptest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+	"upbank-cli/pkg/models"
+)
+
+// filesPrefix is the path attachment files are served under. Like Up's file
+// host, it is outside the API and takes a signed URL instead of the token.
+const filesPrefix = "/files"
+
+// fileURLLifetime is how long a pre-signed file URL stays valid.
+const fileURLLifetime = 10 * time.Minute
+
+// File is an attachment fixture: a file, such as a receipt, attached to a
+// transaction.
+type File struct {
+	ID          string
+	Transaction string
+	// ContentType is the MIME type of Data, e.g. image/jpeg
+	ContentType string
+	// Extension is the file extension without a dot, e.g. jpg
+	Extension string
+	Data      []byte
+}
+
+func (s *Server) listAttachments(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	attachments := make([]models.Attachment, len(s.files))
+	for i, f := range s.files {
+		attachments[i] = s.attachment(f)
+	}
+	s.mu.Unlock()
+	paginate(s, w, r, attachments)
+}
+
+func (s *Server) getAttachment(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.fileIndex(r.PathValue("id"))
+	if i < 0 {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.AttachmentResponse{Data: s.attachment(s.files[i])})
+}
+
+// downloadFile serves the file of an attachment to a pre-signed URL. Expired
+// or tampered URLs are refused with 403, as by Up's file host.
+func (s *Server) downloadFile(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	q := r.URL.Query()
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err != nil || !hmac.Equal([]byte(q.Get("signature")), []byte(s.fileSignature(id, expires))) {
+		http.Error(w, "Invalid signature", http.StatusForbidden)
+		return
+	}
+	if time.Now().Unix() > expires {
+		http.Error(w, "Request has expired", http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	i := s.fileIndex(id)
+	var f File
+	if i >= 0 {
+		f = s.files[i]
+	}
+	s.mu.Unlock()
+	if i < 0 {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", f.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(f.Data)))
+	_, _ = w.Write(f.Data)
+}
+
+// attachment describes a file as the API does, with a freshly signed URL.
+// The caller must hold s.mu.
+func (s *Server) attachment(f File) models.Attachment {
+	expiresAt := time.Now().Add(fileURLLifetime).Truncate(time.Second)
+	fileURL := s.URL + filesPrefix + "/" + url.PathEscape(f.ID) + "?" + url.Values{
+		"expires":   {strconv.FormatInt(expiresAt.Unix(), 10)},
+		"signature": {s.fileSignature(f.ID, expiresAt.Unix())},
+	}.Encode()
+
+	var a models.Attachment
+	a.Type = "attachments"
+	a.ID = f.ID
+	a.Attributes.FileURL = &fileURL
+	a.Attributes.FileURLExpiresAt = expiresAt
+	a.Attributes.FileExtension = &f.Extension
+	a.Attributes.FileContentType = &f.ContentType
+	if i := s.transactionIndex(f.Transaction); i >= 0 {
+		createdAt := s.transactions[i].Attributes.CreatedAt
+		a.Attributes.CreatedAt = &createdAt
+	}
+	a.Relations.Transaction.Data.Type = "transactions"
+	a.Relations.Transaction.Data.ID = f.Transaction
+	a.Relations.Transaction.Links.Related = s.BaseURL() + "/transactions/" + f.Transaction
+	a.Links.Self = s.BaseURL() + "/attachments/" + f.ID
+	return a
+}
+
+// fileSignature signs the URL of a file until expires, a Unix time.
+func (s *Server) fileSignature(id string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(s.token))
+	mac.Write([]byte(id + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// fileIndex returns the index of an attachment, or -1. The caller must hold
+// s.mu.
+func (s *Server) fileIndex(id string) int {
+	return slices.IndexFunc(s.files, func(f File) bool { return f.ID == id })
+}
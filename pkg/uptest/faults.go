@@ -0,0 +1,109 @@
+package uptest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fault describes a failure injected into the responses of a Server.
+// Requests are matched by method, path and page; a matching request is
+// delayed by Delay and then answered with Status, or with a malformed body,
+// instead of reaching the API. A fault with only Delay set slows requests
+// down without failing them.
+type Fault struct {
+	// Method matches the request method; empty matches any
+	Method string
+	// Path matches API paths with this prefix, e.g. "/transactions", or
+	// "/files" for attachment downloads; empty matches any
+	Path string
+	// Page matches the nth page (starting at 1) of a list; zero matches any
+	Page int
+	// Status is the error status to respond with, e.g. 429 or 503
+	Status int
+	// RetryAfter is sent as the Retry-After header, in whole seconds
+	RetryAfter time.Duration
+	// Delay is how long to wait before responding
+	Delay time.Duration
+	// Malformed responds 200 with a truncated JSON body
+	Malformed bool
+	// Times is how many requests the fault applies to; zero means every one
+	Times int
+}
+
+// Inject adds a fault. Faults are matched in the order they were injected.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes every injected fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// matchFault returns the first fault that applies to r and uses up one of
+// its Times. The caller must hold s.mu.
+func (s *Server) matchFault(r *http.Request) *Fault {
+	path := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	for i, f := range s.faults {
+		if f.Method != "" && !strings.EqualFold(f.Method, r.Method) {
+			continue
+		}
+		if !strings.HasPrefix(path, f.Path) {
+			continue
+		}
+		if f.Page > 0 && pageNumber(r) != f.Page {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// pageNumber returns which page of a list r asks for, starting at 1.
+func pageNumber(r *http.Request) int {
+	offset, size, err := pageBounds(r)
+	if err != nil {
+		return 0
+	}
+	return offset/size + 1
+}
+
+// apply delays the request and writes the fault's response, reporting
+// whether the request was answered.
+func (f *Fault) apply(w http.ResponseWriter, r *http.Request) bool {
+	if f.Delay > 0 {
+		timer := time.NewTimer(f.Delay)
+		defer timer.Stop()
+		select {
+		case <-r.Context().Done():
+			return true
+		case <-timer.C:
+		}
+	}
+
+	switch {
+	case f.Status != 0:
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter.Round(time.Second)/time.Second)))
+		}
+		writeError(w, f.Status, http.StatusText(f.Status), "Injected fault.", "")
+		return true
+	case f.Malformed:
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		_, _ = w.Write([]byte(`{"data": [{"type": "transactions", "id": `))
+		return true
+	}
+	return false
+}
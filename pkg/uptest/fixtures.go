@@ -0,0 +1,205 @@
+package uptest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"upbank-cli/pkg/models"
+)
+
+// Fixtures is the data a Server is seeded with.
+type Fixtures struct {
+	Accounts     []models.Account
+	Transactions []models.Transaction
+	Categories   []models.Category
+	Webhooks     []models.Webhook
+	Attachments  []File
+}
+
+// TransactionSpec describes a transaction for NewTransaction. Empty fields
+// are left out of the transaction.
+type TransactionSpec struct {
+	ID          string
+	Account     string
+	Description string
+	Message     string
+	// Status defaults to SETTLED
+	Status string
+	// Amount is a decimal string such as "-12.34"
+	Amount string
+	// Currency defaults to AUD
+	Currency        string
+	ForeignAmount   string
+	ForeignCurrency string
+	// Category is a child category ID. The parent category is filled in by
+	// the Server from its categories.
+	Category  string
+	Tags      []string
+	CreatedAt time.Time
+}
+
+// setIdentifier stores a {type, id} resource identifier in a relationship's
+// data field, whatever its exact Go type. An empty id stores null.
+func setIdentifier(data any, resourceType, id string) {
+	raw := []byte("null")
+	if id != "" {
+		raw, _ = json.Marshal(map[string]string{"type": resourceType, "id": id})
+	}
+	_ = json.Unmarshal(raw, data)
+}
+
+// setIdentifiers stores a list of resource identifiers in a relationship's
+// data field.
+func setIdentifiers(data any, resourceType string, ids []string) {
+	list := make([]map[string]string, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, map[string]string{"type": resourceType, "id": id})
+	}
+	raw, _ := json.Marshal(list)
+	_ = json.Unmarshal(raw, data)
+}
+
+// money builds a MoneyObject from a decimal string.
+func money(value, currency string) models.MoneyObject {
+	f, _ := strconv.ParseFloat(value, 64)
+	return models.MoneyObject{
+		CurrencyCode:     currency,
+		Value:            value,
+		ValueInBaseUnits: int64(math.Round(f * 100)),
+	}
+}
+
+// NewAccount builds an individual account with a balance given as a decimal
+// string.
+func NewAccount(id, name, accountType, balance string) models.Account {
+	var a models.Account
+	a.Type = "accounts"
+	a.ID = id
+	a.Attributes.DisplayName = name
+	a.Attributes.AccountType = accountType
+	a.Attributes.OwnershipType = "INDIVIDUAL"
+	a.Attributes.Balance = models.Balance(money(balance, "AUD"))
+	a.Attributes.CreatedAt = "2020-01-01T00:00:00+10:00"
+	return a
+}
+
+// NewTransaction builds a transaction from a spec.
+func NewTransaction(spec TransactionSpec) models.Transaction {
+	if spec.Status == "" {
+		spec.Status = "SETTLED"
+	}
+	if spec.Currency == "" {
+		spec.Currency = "AUD"
+	}
+
+	var tx models.Transaction
+	tx.Type = "transactions"
+	tx.ID = spec.ID
+	tx.Attributes.Status = spec.Status
+	tx.Attributes.Description = spec.Description
+	tx.Attributes.Message = spec.Message
+	tx.Attributes.IsCategorizable = true
+	tx.Attributes.Amount = money(spec.Amount, spec.Currency)
+	if spec.ForeignAmount != "" {
+		foreign := money(spec.ForeignAmount, spec.ForeignCurrency)
+		tx.Attributes.ForeignAmount = &foreign
+	}
+	tx.Attributes.CreatedAt = spec.CreatedAt
+	if spec.Status == "SETTLED" {
+		tx.Attributes.SettledAt = spec.CreatedAt.Add(time.Hour)
+	}
+
+	tx.Relations.Account.Data.Type = "accounts"
+	tx.Relations.Account.Data.ID = spec.Account
+	setIdentifier(&tx.Relations.Category.Data, "categories", spec.Category)
+	setIdentifiers(&tx.Relations.Tags.Data, "tags", spec.Tags)
+	return tx
+}
+
+// NewCategory builds a category. An empty parent makes it a parent
+// category; children are filled in by the Server.
+func NewCategory(id, name, parent string) models.Category {
+	var c models.Category
+	c.Type = "categories"
+	c.ID = id
+	c.Attributes.Name = name
+	setIdentifier(&c.Relations.Parent.Data, "categories", parent)
+	return c
+}
+
+// DefaultFixtures returns a small, fixed data set: a spending and a saver
+// account, a handful of categories, 25 purchases spread over January and
+// February 2024 (some tagged, one held, one in a foreign currency with a
+// receipt attached), a salary payment and a transfer to savings.
+func DefaultFixtures() Fixtures {
+	f := Fixtures{
+		Accounts: []models.Account{
+			NewAccount("acc-spending", "Spending", "TRANSACTIONAL", "1234.56"),
+			NewAccount("acc-savings", "Rainy Day", "SAVER", "10000.00"),
+		},
+		Categories: []models.Category{
+			NewCategory("good-life", "Good Life", ""),
+			NewCategory("restaurants-and-cafes", "Restaurants & Cafes", "good-life"),
+			NewCategory("pubs-and-bars", "Pubs & Bars", "good-life"),
+			NewCategory("home", "Home", ""),
+			NewCategory("groceries", "Groceries", "home"),
+			NewCategory("transport", "Transport", ""),
+			NewCategory("public-transport", "Public Transport", "transport"),
+		},
+	}
+
+	merchants := []struct {
+		description, amount, category string
+	}{
+		{"Woolworths", "-54.20", "groceries"},
+		{"Coffee Supreme", "-4.50", "restaurants-and-cafes"},
+		{"Opal Travel", "-3.20", "public-transport"},
+		{"The Local Pub", "-32.00", "pubs-and-bars"},
+		{"Coles", "-87.15", "groceries"},
+	}
+	sydney := time.FixedZone("AEDT", 11*60*60)
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, sydney)
+	for i := range 25 {
+		m := merchants[i%len(merchants)]
+		spec := TransactionSpec{
+			ID:          fmt.Sprintf("tx-%02d", i+1),
+			Account:     "acc-spending",
+			Description: m.description,
+			Amount:      m.amount,
+			Category:    m.category,
+			CreatedAt:   start.Add(time.Duration(i) * 50 * time.Hour),
+		}
+		switch {
+		case i%7 == 3:
+			spec.Tags = []string{"work"}
+		case i%11 == 5:
+			spec.Tags = []string{"holiday", "work"}
+		}
+		if i == 24 {
+			spec.Status = "HELD"
+		}
+		if i == 12 {
+			spec.Description = "Kissa Tanto Tokyo"
+			spec.Amount, spec.ForeignAmount, spec.ForeignCurrency = "-27.80", "-2750", "JPY"
+			spec.Category = "restaurants-and-cafes"
+			f.Attachments = append(f.Attachments, File{
+				ID:          "att-receipt",
+				Transaction: spec.ID,
+				ContentType: "image/jpeg",
+				Extension:   "jpg",
+				Data:        []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00 receipt \xff\xd9"),
+			})
+		}
+		f.Transactions = append(f.Transactions, NewTransaction(spec))
+	}
+
+	// A salary payment into spending and a transfer to savings
+	payday := start.Add(14 * 24 * time.Hour)
+	f.Transactions = append(f.Transactions,
+		NewTransaction(TransactionSpec{ID: "tx-salary", Account: "acc-spending", Description: "Salary", Amount: "3500.00", CreatedAt: payday}),
+		NewTransaction(TransactionSpec{ID: "tx-transfer", Account: "acc-savings", Description: "Transfer from Spending", Message: "Saving", Amount: "500.00", CreatedAt: payday.Add(time.Minute)}),
+	)
+	return f
+}
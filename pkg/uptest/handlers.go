@@ -0,0 +1,308 @@
+package uptest
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"time"
+	"upbank-cli/pkg/models"
+)
+
+// Values accepted by the enum filters, as documented by Up.
+var (
+	accountTypes      = []string{"SAVER", "TRANSACTIONAL", "HOME_LOAN"}
+	ownershipTypes    = []string{"INDIVIDUAL", "JOINT"}
+	transactionStates = []string{"HELD", "SETTLED"}
+)
+
+// maxTags is the number of tags Up allows on a single transaction.
+const maxTags = 6
+
+// checkEnum writes an error and reports false if the filter is set to a
+// value outside allowed.
+func checkEnum(w http.ResponseWriter, q url.Values, parameter string, allowed []string) bool {
+	v := q.Get(parameter)
+	if v == "" || slices.Contains(allowed, v) {
+		return true
+	}
+	invalidParameter(w, parameter, "Unknown value "+v+".")
+	return false
+}
+
+func (s *Server) listAccounts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if !checkEnum(w, q, "filter[accountType]", accountTypes) || !checkEnum(w, q, "filter[ownershipType]", ownershipTypes) {
+		return
+	}
+
+	s.mu.Lock()
+	var accounts []models.Account
+	for _, a := range s.accounts {
+		if v := q.Get("filter[accountType]"); v != "" && a.Attributes.AccountType != v {
+			continue
+		}
+		if v := q.Get("filter[ownershipType]"); v != "" && a.Attributes.OwnershipType != v {
+			continue
+		}
+		accounts = append(accounts, a)
+	}
+	s.mu.Unlock()
+	paginate(s, w, r, accounts)
+}
+
+func (s *Server) getAccount(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.accounts, func(a models.Account) bool { return a.ID == r.PathValue("id") })
+	if i < 0 {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.AccountResponse{Data: s.accounts[i]})
+}
+
+// listTransactions serves both /transactions and the transactions of a
+// single account.
+func (s *Server) listTransactions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if !checkEnum(w, q, "filter[status]", transactionStates) {
+		return
+	}
+	var since, until time.Time
+	for parameter, t := range map[string]*time.Time{"filter[since]": &since, "filter[until]": &until} {
+		if v := q.Get(parameter); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				invalidParameter(w, parameter, "Must be an RFC 3339 date-time.")
+				return
+			}
+			*t = parsed
+		}
+	}
+
+	s.mu.Lock()
+	accountID := r.PathValue("id")
+	if accountID != "" && !slices.ContainsFunc(s.accounts, func(a models.Account) bool { return a.ID == accountID }) {
+		s.mu.Unlock()
+		notFound(w)
+		return
+	}
+	category := q.Get("filter[category]")
+	if category != "" && s.categoryIndex(category) < 0 {
+		s.mu.Unlock()
+		invalidParameter(w, "filter[category]", "Unknown category "+category+".")
+		return
+	}
+
+	var transactions []models.Transaction
+	for _, tx := range s.transactions {
+		if accountID != "" && tx.Relations.Account.Data.ID != accountID {
+			continue
+		}
+		if v := q.Get("filter[status]"); v != "" && tx.Attributes.Status != v {
+			continue
+		}
+		if !since.IsZero() && tx.Attributes.CreatedAt.Before(since) {
+			continue
+		}
+		// Both bounds are inclusive, as in the Up API
+		if !until.IsZero() && tx.Attributes.CreatedAt.After(until) {
+			continue
+		}
+		if category != "" && categoryID(tx) != category && parentCategoryID(tx) != category {
+			continue
+		}
+		if tag := q.Get("filter[tag]"); tag != "" && !slices.Contains(tagIDs(tx), tag) {
+			continue
+		}
+		transactions = append(transactions, tx)
+	}
+	s.mu.Unlock()
+	paginate(s, w, r, transactions)
+}
+
+func (s *Server) getTransaction(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.transactionIndex(r.PathValue("id"))
+	if i < 0 {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.TransactionResponse{Data: s.transactions[i]})
+}
+
+// categorize sets or, with null data, removes the category of a transaction.
+func (s *Server) categorize(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Data *struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"data"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.transactionIndex(r.PathValue("id"))
+	if i < 0 {
+		notFound(w)
+		return
+	}
+	tx := &s.transactions[i]
+	if !tx.Attributes.IsCategorizable {
+		writeError(w, http.StatusForbidden, "Forbidden", "This transaction cannot be categorized.", "")
+		return
+	}
+
+	var id string
+	if body.Data != nil {
+		id = body.Data.ID
+		c := s.categoryIndex(id)
+		if c < 0 || s.categories[c].ParentID() == "" {
+			writeError(w, http.StatusUnprocessableEntity, "Invalid Category", "Transactions can only be categorized with a child category.", "")
+			return
+		}
+	}
+	s.setCategory(tx, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// updateTags adds tags to a transaction on POST and removes them on DELETE.
+func (s *Server) updateTags(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Data []struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"data"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.transactionIndex(r.PathValue("id"))
+	if i < 0 {
+		notFound(w)
+		return
+	}
+	tx := &s.transactions[i]
+
+	tags := tagIDs(*tx)
+	for _, t := range body.Data {
+		if r.Method == http.MethodDelete {
+			tags = slices.DeleteFunc(tags, func(id string) bool { return id == t.ID })
+		} else if !slices.Contains(tags, t.ID) {
+			tags = append(tags, t.ID)
+		}
+	}
+	if len(tags) > maxTags {
+		writeError(w, http.StatusUnprocessableEntity, "Too Many Tags", "A transaction can have at most 6 tags.", "")
+		return
+	}
+	setIdentifiers(&tx.Relations.Tags.Data, "tags", tags)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listCategories(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parent := r.URL.Query().Get("filter[parent]")
+	if parent != "" && s.categoryIndex(parent) < 0 {
+		invalidParameter(w, "filter[parent]", "Unknown category "+parent+".")
+		return
+	}
+	categories := []models.Category{}
+	for _, c := range s.categories {
+		if parent == "" || c.ParentID() == parent {
+			categories = append(categories, c)
+		}
+	}
+	writeJSON(w, http.StatusOK, models.CategoriesResponse{Data: categories})
+}
+
+func (s *Server) getCategory(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.categoryIndex(r.PathValue("id"))
+	if i < 0 {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.CategoryResponse{Data: s.categories[i]})
+}
+
+// listTags lists every tag in use, in alphabetical order.
+func (s *Server) listTags(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	var ids []string
+	for _, tx := range s.transactions {
+		for _, id := range tagIDs(tx) {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	s.mu.Unlock()
+	sort.Strings(ids)
+
+	tags := make([]models.Tag, len(ids))
+	for i, id := range ids {
+		tags[i].Type = "tags"
+		tags[i].ID = id
+	}
+	paginate(s, w, r, tags)
+}
+
+// transactionIndex returns the index of a transaction, or -1. The caller
+// must hold s.mu.
+func (s *Server) transactionIndex(id string) int {
+	return slices.IndexFunc(s.transactions, func(tx models.Transaction) bool { return tx.ID == id })
+}
+
+// categoryIndex returns the index of a category, or -1. The caller must hold
+// s.mu.
+func (s *Server) categoryIndex(id string) int {
+	return slices.IndexFunc(s.categories, func(c models.Category) bool { return c.ID == id })
+}
+
+// setCategory sets the category of tx and the parent category that goes
+// with it. An empty id removes both. The caller must hold s.mu.
+func (s *Server) setCategory(tx *models.Transaction, id string) {
+	var parent string
+	if i := s.categoryIndex(id); i >= 0 {
+		parent = s.categories[i].ParentID()
+	}
+	setIdentifier(&tx.Relations.Category.Data, "categories", id)
+	setIdentifier(&tx.Relations.ParentCategory.Data, "categories", parent)
+}
+
+// categoryID returns the category of tx, or "".
+func categoryID(tx models.Transaction) string {
+	if tx.Relations.Category.Data == nil {
+		return ""
+	}
+	return tx.Relations.Category.Data.ID
+}
+
+// parentCategoryID returns the parent category of tx, or "".
+func parentCategoryID(tx models.Transaction) string {
+	if tx.Relations.ParentCategory.Data == nil {
+		return ""
+	}
+	return tx.Relations.ParentCategory.Data.ID
+}
+
+// tagIDs returns the tags of tx.
+func tagIDs(tx models.Transaction) []string {
+	ids := make([]string, 0, len(tx.Relations.Tags.Data))
+	for _, t := range tx.Relations.Tags.Data {
+		ids = append(ids, t.ID)
+	}
+	return ids
+}
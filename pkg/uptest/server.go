@@ -0,0 +1,309 @@
+// Package uptest provides an in-process fake of the Up API for hermetic
+// tests of tools built on it, including this CLI. The fake serves accounts,
+// transactions, categories, tags, attachments, webhooks and ping from seeded
+// fixtures, paginates with real links.next URLs, applies the same filters as
+// Up and can inject faults such as rate limiting, server errors, slow pages
+// and malformed responses.
+//
+//	srv := uptest.NewServer(uptest.DefaultFixtures())
+//	defer srv.Close()
+//	client, _ := api.NewClient(api.WithBaseURL(srv.BaseURL()), api.WithToken(uptest.DefaultToken, "test"))
+package uptest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"upbank-cli/pkg/models"
+)
+
+// DefaultToken is the API token a Server accepts unless WithToken is used.
+const DefaultToken = "up:yeah:uptest"
+
+// Page sizes used by the Up API.
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// apiPrefix is the path every API endpoint lives under.
+const apiPrefix = "/api/v1"
+
+// Server is a fake Up API server. It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+	token string
+
+	mu           sync.Mutex
+	accounts     []models.Account
+	transactions []models.Transaction
+	categories   []models.Category
+	webhooks     []models.Webhook
+	files        []File
+	secrets      map[string]string
+	logs         map[string][]models.WebhookDeliveryLog
+	faults       []*Fault
+	requests     []string
+	seq          int
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithToken sets the API token the server accepts.
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// NewServer starts a server seeded with f. Call Close when done.
+func NewServer(f Fixtures, opts ...Option) *Server {
+	s := &Server{
+		token:        DefaultToken,
+		accounts:     slices.Clone(f.Accounts),
+		transactions: slices.Clone(f.Transactions),
+		categories:   slices.Clone(f.Categories),
+		webhooks:     slices.Clone(f.Webhooks),
+		files:        slices.Clone(f.Attachments),
+		secrets:      make(map[string]string),
+		logs:         make(map[string][]models.WebhookDeliveryLog),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// Fill in the relationships derived from other fixtures
+	for i := range s.categories {
+		var children []string
+		for _, c := range s.categories {
+			if c.ParentID() == s.categories[i].ID {
+				children = append(children, c.ID)
+			}
+		}
+		setIdentifiers(&s.categories[i].Relations.Children.Data, "categories", children)
+	}
+	for i := range s.transactions {
+		s.setCategory(&s.transactions[i], categoryID(s.transactions[i]))
+	}
+	for _, f := range s.files {
+		if i := s.transactionIndex(f.Transaction); i >= 0 {
+			setIdentifier(&s.transactions[i].Relations.Attachment.Data, "attachments", f.ID)
+		}
+	}
+	sort.Stable(models.ByDate(s.transactions))
+
+	mux := http.NewServeMux()
+	s.routes(mux)
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+// BaseURL returns the API root to point a client at.
+func (s *Server) BaseURL() string {
+	return s.URL + apiPrefix
+}
+
+// Requests returns every request received so far as "METHOD /path?query",
+// in the order they arrived.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// Transaction returns the current state of a transaction, e.g. to check the
+// effect of categorizing or tagging it.
+func (s *Server) Transaction(id string) (models.Transaction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.transactionIndex(id)
+	if i < 0 {
+		return models.Transaction{}, false
+	}
+	return s.transactions[i], true
+}
+
+// AddTransaction adds a transaction, as if it had just happened.
+func (s *Server) AddTransaction(tx models.Transaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setCategory(&tx, categoryID(tx))
+	s.transactions = append(s.transactions, tx)
+	sort.Stable(models.ByDate(s.transactions))
+}
+
+func (s *Server) routes(mux *http.ServeMux) {
+	mux.HandleFunc("GET "+apiPrefix+"/util/ping", s.ping)
+
+	mux.HandleFunc("GET "+apiPrefix+"/accounts", s.listAccounts)
+	mux.HandleFunc("GET "+apiPrefix+"/accounts/{id}", s.getAccount)
+	mux.HandleFunc("GET "+apiPrefix+"/accounts/{id}/transactions", s.listTransactions)
+
+	mux.HandleFunc("GET "+apiPrefix+"/transactions", s.listTransactions)
+	mux.HandleFunc("GET "+apiPrefix+"/transactions/{id}", s.getTransaction)
+	mux.HandleFunc("PATCH "+apiPrefix+"/transactions/{id}/relationships/category", s.categorize)
+	mux.HandleFunc("POST "+apiPrefix+"/transactions/{id}/relationships/tags", s.updateTags)
+	mux.HandleFunc("DELETE "+apiPrefix+"/transactions/{id}/relationships/tags", s.updateTags)
+
+	mux.HandleFunc("GET "+apiPrefix+"/categories", s.listCategories)
+	mux.HandleFunc("GET "+apiPrefix+"/categories/{id}", s.getCategory)
+	mux.HandleFunc("GET "+apiPrefix+"/tags", s.listTags)
+
+	mux.HandleFunc("GET "+apiPrefix+"/attachments", s.listAttachments)
+	mux.HandleFunc("GET "+apiPrefix+"/attachments/{id}", s.getAttachment)
+	mux.HandleFunc("GET "+filesPrefix+"/{id}", s.downloadFile)
+
+	mux.HandleFunc("GET "+apiPrefix+"/webhooks", s.listWebhooks)
+	mux.HandleFunc("POST "+apiPrefix+"/webhooks", s.createWebhook)
+	mux.HandleFunc("GET "+apiPrefix+"/webhooks/{id}", s.getWebhook)
+	mux.HandleFunc("DELETE "+apiPrefix+"/webhooks/{id}", s.deleteWebhook)
+	mux.HandleFunc("POST "+apiPrefix+"/webhooks/{id}/ping", s.pingWebhook)
+	mux.HandleFunc("GET "+apiPrefix+"/webhooks/{id}/logs", s.listWebhookLogs)
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "Not Found", "The resource was not found.", "")
+	})
+}
+
+// middleware records requests, injects faults and checks the token before
+// passing requests to the API handlers. Files are authorized by their
+// signed URL instead, and must not be sent the token.
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+		fault := s.matchFault(r)
+		s.mu.Unlock()
+
+		if fault != nil && fault.apply(w, r) {
+			return
+		}
+
+		if strings.HasPrefix(r.URL.Path, filesPrefix+"/") {
+			if r.Header.Get("Authorization") != "" {
+				http.Error(w, "Only one auth mechanism allowed", http.StatusBadRequest)
+				return
+			}
+		} else if r.Header.Get("Authorization") != "Bearer "+s.token {
+			writeError(w, http.StatusUnauthorized, "Not Authorized",
+				"The request was not authenticated because no valid credential was found in the Authorization header, or the Authorization header was not present.", "")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ping answers /util/ping like Up does.
+func (s *Server) ping(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	id := s.nextID("ping")
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"meta": map[string]string{"id": id, "statusEmoji": "⚡️"},
+	})
+}
+
+// nextID returns a new unique ID. The caller must hold s.mu.
+func (s *Server) nextID(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s-%04d", prefix, s.seq)
+}
+
+// writeJSON writes v as the JSON response body.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/vnd.api+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON:API error. parameter names the query parameter
+// at fault, if any.
+func writeError(w http.ResponseWriter, status int, title, detail, parameter string) {
+	object := map[string]any{
+		"status": strconv.Itoa(status),
+		"title":  title,
+		"detail": detail,
+	}
+	if parameter != "" {
+		object["source"] = map[string]string{"parameter": parameter}
+	}
+	writeJSON(w, status, map[string]any{"errors": []any{object}})
+}
+
+// invalidParameter writes the error Up returns for a bad query parameter.
+func invalidParameter(w http.ResponseWriter, parameter, detail string) {
+	writeError(w, http.StatusBadRequest, "Invalid Request Parameter", detail, parameter)
+}
+
+// notFound writes the error Up returns for a missing resource.
+func notFound(w http.ResponseWriter) {
+	writeError(w, http.StatusNotFound, "Not Found", "The resource was not found.", "")
+}
+
+// decodeBody reads a JSON request body into v.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid Request Body", "The request body is not valid JSON: "+err.Error(), "")
+		return false
+	}
+	return true
+}
+
+// pageBounds reads page[size] and the page[after] cursor of a list request.
+func pageBounds(r *http.Request) (offset, size int, err error) {
+	size = defaultPageSize
+	if v := r.URL.Query().Get("page[size]"); v != "" {
+		size, err = strconv.Atoi(v)
+		if err != nil || size < 1 || size > maxPageSize {
+			return 0, 0, fmt.Errorf("page[size]: must be between 1 and %d", maxPageSize)
+		}
+	}
+	if v := r.URL.Query().Get("page[after]"); v != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(v)
+		if err == nil {
+			offset, err = strconv.Atoi(string(raw))
+		}
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("page[after]: invalid cursor")
+		}
+	}
+	return offset, size, nil
+}
+
+// paginate writes one page of items, with a links.next URL when more pages
+// follow. The cursor is opaque to clients, like Up's.
+func paginate[T any](s *Server, w http.ResponseWriter, r *http.Request, items []T) {
+	offset, size, err := pageBounds(r)
+	if err != nil {
+		parameter, _, _ := strings.Cut(err.Error(), ":")
+		invalidParameter(w, parameter, err.Error())
+		return
+	}
+
+	offset = min(offset, len(items))
+	end := min(offset+size, len(items))
+	var links models.PageLinks
+	if end < len(items) {
+		q := r.URL.Query()
+		q.Set("page[after]", base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(end))))
+		next := s.URL + r.URL.Path + "?" + q.Encode()
+		links.Next = &next
+	}
+
+	data := items[offset:end]
+	if data == nil {
+		data = []T{}
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Data  []T              `json:"data"`
+		Links models.PageLinks `json:"links"`
+	}{data, links})
+}
@@ -0,0 +1,565 @@
+package uptest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"upbank-cli/pkg/api"
+	"upbank-cli/pkg/models"
+	"upbank-cli/pkg/uptest"
+	"upbank-cli/pkg/webhook"
+)
+
+// newServer starts a server with the default fixtures and a client for it
+// that doesn't retry, so faults reach the test.
+func newServer(t *testing.T, opts ...api.Option) (*uptest.Server, *api.Client) {
+	t.Helper()
+	srv := uptest.NewServer(uptest.DefaultFixtures())
+	t.Cleanup(srv.Close)
+	opts = append([]api.Option{
+		api.WithBaseURL(srv.BaseURL()),
+		api.WithToken(uptest.DefaultToken, "test"),
+		api.WithRetryPolicy(api.RetryPolicy{}),
+	}, opts...)
+	client, err := api.NewClient(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+// get sends an authenticated request to the server and returns the status,
+// headers and body of the response.
+func get(t *testing.T, srv *uptest.Server, rawURL string) (int, http.Header, []byte) {
+	t.Helper()
+	if !strings.HasPrefix(rawURL, "http") {
+		rawURL = srv.BaseURL() + rawURL
+	}
+	req, _ := http.NewRequest(http.MethodGet, rawURL, nil)
+	req.Header.Set("Authorization", "Bearer "+uptest.DefaultToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, resp.Header, body
+}
+
+// ids returns the IDs of the transactions.
+func ids(transactions []models.Transaction) []string {
+	var ids []string
+	for _, tx := range transactions {
+		ids = append(ids, tx.ID)
+	}
+	return ids
+}
+
+func TestPagination(t *testing.T) {
+	srv, _ := newServer(t)
+
+	var got []string
+	pages := 0
+	next := "/transactions?page%5Bsize%5D=10"
+	for next != "" {
+		status, _, body := get(t, srv, next)
+		if status != http.StatusOK {
+			t.Fatalf("GET %s: status %d: %s", next, status, body)
+		}
+		var page struct {
+			Data  []models.Transaction `json:"data"`
+			Links models.PageLinks     `json:"links"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		got = append(got, ids(page.Data)...)
+		next = ""
+		if page.Links.Next != nil {
+			next = *page.Links.Next
+			if !strings.HasPrefix(next, srv.BaseURL()+"/transactions?") {
+				t.Errorf("links.next = %s, want an absolute transactions URL", next)
+			}
+			if u, _ := url.Parse(next); u.Query().Get("page[size]") != "10" {
+				t.Errorf("links.next %s lost the page size", next)
+			}
+		}
+	}
+
+	if pages != 3 || len(got) != 27 {
+		t.Fatalf("got %d transactions in %d pages, want 27 in 3", len(got), pages)
+	}
+	if got[0] != "tx-25" || got[26] != "tx-01" {
+		t.Errorf("transactions run from %s to %s, want newest first", got[0], got[26])
+	}
+	slices.Sort(got)
+	if len(slices.Compact(got)) != 27 {
+		t.Errorf("pages overlap: %v", got)
+	}
+
+	for _, query := range []string{"page%5Bsize%5D=101", "page%5Bsize%5D=0", "page%5Bafter%5D=not-a-cursor"} {
+		if status, _, body := get(t, srv, "/transactions?"+query); status != http.StatusBadRequest {
+			t.Errorf("GET /transactions?%s: status %d, want 400: %s", query, status, body)
+		}
+	}
+}
+
+func TestReplayAnotherBaseURL(t *testing.T) {
+	dir := t.TempDir()
+	srv, recorder := newServer(t, api.WithRecorder(dir))
+	ctx := context.Background()
+	filter := api.TransactionFilter{PageSize: 10}
+	recorded, err := recorder.GetTransactions(ctx, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 27 {
+		t.Fatalf("recorded %d transactions, want 27", len(recorded))
+	}
+
+	// links.next points at the recording server, which is gone
+	srv.Close()
+	replayer, err := api.NewClient(
+		api.WithToken("", ""),
+		api.WithBaseURL("http://other.example/up/v2"),
+		api.WithReplay(dir),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := replayer.GetTransactions(ctx, filter)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if !slices.Equal(ids(replayed), ids(recorded)) {
+		t.Errorf("replayed %v, want %v", ids(replayed), ids(recorded))
+	}
+}
+
+func TestTransactionFilters(t *testing.T) {
+	_, client := newServer(t)
+	ctx := context.Background()
+	sydney := time.FixedZone("AEDT", 11*60*60)
+
+	tests := []struct {
+		name    string
+		account string
+		filter  api.TransactionFilter
+		want    []string
+	}{
+		{"status", "", api.TransactionFilter{Status: api.StatusHeld}, []string{"tx-25"}},
+		// tx-02 is on the since bound and tx-04 on the until bound
+		{"since and until inclusive", "", api.TransactionFilter{
+			Since: time.Date(2024, 1, 3, 11, 0, 0, 0, sydney),
+			Until: time.Date(2024, 1, 7, 15, 0, 0, 0, sydney),
+		}, []string{"tx-04", "tx-03", "tx-02"}},
+		{"category", "", api.TransactionFilter{Category: "pubs-and-bars"}, []string{"tx-24", "tx-19", "tx-14", "tx-09", "tx-04"}},
+		{"parent category", "", api.TransactionFilter{Category: "transport"}, []string{"tx-23", "tx-18", "tx-08", "tx-03"}},
+		{"tag", "", api.TransactionFilter{Tag: "holiday"}, []string{"tx-17", "tx-06"}},
+		{"account", "acc-savings", api.TransactionFilter{}, []string{"tx-transfer"}},
+		{"account and status", "acc-spending", api.TransactionFilter{Status: api.StatusHeld}, []string{"tx-25"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []models.Transaction
+			var err error
+			if tt.account != "" {
+				got, err = client.GetAccountTransactions(ctx, tt.account, tt.filter)
+			} else {
+				got, err = client.GetTransactions(ctx, tt.filter)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ids(got), tt.want) {
+				t.Errorf("got %v, want %v", ids(got), tt.want)
+			}
+		})
+	}
+
+	var apiErr *api.Error
+	_, err := client.GetTransactions(ctx, api.TransactionFilter{Category: "no-such-category"})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown category: error = %v, want 400", err)
+	}
+	_, err = client.GetAccountTransactions(ctx, "no-such-account", api.TransactionFilter{})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown account: error = %v, want 404", err)
+	}
+}
+
+func TestAccountFilters(t *testing.T) {
+	srv, client := newServer(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		filter api.AccountFilter
+		want   []string
+	}{
+		{api.AccountFilter{}, []string{"acc-spending", "acc-savings"}},
+		{api.AccountFilter{AccountType: api.AccountTypeSaver}, []string{"acc-savings"}},
+		{api.AccountFilter{AccountType: api.AccountTypeHomeLoan}, nil},
+		{api.AccountFilter{OwnershipType: api.OwnershipIndividual}, []string{"acc-spending", "acc-savings"}},
+		{api.AccountFilter{OwnershipType: api.OwnershipJoint}, nil},
+	}
+	for _, tt := range tests {
+		accounts, err := client.GetAccounts(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.filter, err)
+		}
+		var got []string
+		for _, a := range accounts {
+			got = append(got, a.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%+v: got %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	// The fake rejects what the client's validation would
+	if status, _, _ := get(t, srv, "/accounts?filter%5BaccountType%5D=BOGUS"); status != http.StatusBadRequest {
+		t.Errorf("unknown account type: status %d, want 400", status)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	t.Run("rate limit", func(t *testing.T) {
+		srv, _ := newServer(t)
+		srv.Inject(uptest.Fault{Path: "/transactions", Page: 2, Status: http.StatusTooManyRequests, RetryAfter: 2 * time.Second, Times: 1})
+
+		if status, _, _ := get(t, srv, "/transactions"); status != http.StatusOK {
+			t.Errorf("page 1: status %d, want 200", status)
+		}
+		page2 := "/transactions?page%5Bafter%5D=MTA"
+		status, header, _ := get(t, srv, page2)
+		if status != http.StatusTooManyRequests || header.Get("Retry-After") != "2" {
+			t.Errorf("page 2: status %d, Retry-After %q, want 429 and 2", status, header.Get("Retry-After"))
+		}
+		if status, _, _ := get(t, srv, page2); status != http.StatusOK {
+			t.Errorf("page 2 once the fault is used up: status %d, want 200", status)
+		}
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		srv, client := newServer(t, api.WithRetryPolicy(api.RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond}))
+		srv.Inject(uptest.Fault{Path: "/accounts", Status: http.StatusServiceUnavailable, Times: 1})
+
+		accounts, err := client.GetAccounts(context.Background(), api.AccountFilter{})
+		if err != nil || len(accounts) != 2 {
+			t.Fatalf("GetAccounts after a 503 = %d accounts, %v; want a successful retry", len(accounts), err)
+		}
+		if n := len(srv.Requests()); n != 2 {
+			t.Errorf("got %d requests, want 2", n)
+		}
+
+		srv.Inject(uptest.Fault{Method: http.MethodGet, Path: "/accounts", Status: http.StatusBadGateway})
+		var apiErr *api.Error
+		_, err = client.GetAccounts(context.Background(), api.AccountFilter{})
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+			t.Errorf("error = %v, want 502", err)
+		}
+		srv.ClearFaults()
+		if _, err := client.GetAccounts(context.Background(), api.AccountFilter{}); err != nil {
+			t.Errorf("after ClearFaults: %v", err)
+		}
+	})
+
+	t.Run("slow page", func(t *testing.T) {
+		srv, client := newServer(t, api.WithRequestTimeout(50*time.Millisecond))
+		srv.Inject(uptest.Fault{Path: "/transactions", Page: 2, Delay: 200 * time.Millisecond, Times: 1})
+
+		// Only the second page is slow
+		start := time.Now()
+		get(t, srv, "/transactions")
+		if elapsed := time.Since(start); elapsed >= 200*time.Millisecond {
+			t.Errorf("first page took %s, want it undelayed", elapsed)
+		}
+		start = time.Now()
+		if status, _, _ := get(t, srv, "/transactions?page%5Bafter%5D=MTA"); status != http.StatusOK {
+			t.Errorf("slow page: status %d, want 200", status)
+		}
+		if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+			t.Errorf("slow page took %s, want at least 200ms", elapsed)
+		}
+
+		srv.Inject(uptest.Fault{Path: "/transactions", Page: 2, Delay: 200 * time.Millisecond, Times: 1})
+		_, err := client.GetTransactions(context.Background(), api.TransactionFilter{})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want the request timeout", err)
+		}
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		srv, client := newServer(t)
+		srv.Inject(uptest.Fault{Path: "/accounts", Malformed: true})
+
+		status, _, body := get(t, srv, "/accounts")
+		if status != http.StatusOK || json.Valid(body) {
+			t.Errorf("status %d, body %s: want 200 with invalid JSON", status, body)
+		}
+		if _, err := client.GetAccounts(context.Background(), api.AccountFilter{}); err == nil {
+			t.Error("GetAccounts succeeded on a malformed body")
+		}
+	})
+}
+
+// countRequests returns how many requests the server received for method
+// and an API path starting with path.
+func countRequests(srv *uptest.Server, method, path string) int {
+	n := 0
+	for _, r := range srv.Requests() {
+		if strings.HasPrefix(r, method+" /api/v1"+path) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Retry-After beyond the backoff cap", func(t *testing.T) {
+		srv, client := newServer(t, api.WithRetryPolicy(api.RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}))
+		srv.Inject(uptest.Fault{Path: "/accounts", Status: http.StatusTooManyRequests, RetryAfter: time.Second, Times: 1})
+
+		start := time.Now()
+		if _, err := client.GetAccounts(ctx, api.AccountFilter{}); err != nil {
+			t.Fatalf("GetAccounts after a 429: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("retried after %s, want Retry-After's 1s", elapsed)
+		}
+		if n := countRequests(srv, http.MethodGet, "/accounts"); n != 2 {
+			t.Errorf("got %d requests, want 2", n)
+		}
+	})
+
+	t.Run("backoff cap", func(t *testing.T) {
+		srv, client := newServer(t, api.WithRetryPolicy(api.RetryPolicy{MaxRetries: 3, BaseDelay: time.Minute, MaxDelay: 20 * time.Millisecond}))
+		srv.Inject(uptest.Fault{Path: "/accounts", Status: http.StatusServiceUnavailable, Times: 3})
+
+		start := time.Now()
+		if _, err := client.GetAccounts(ctx, api.AccountFilter{}); err != nil {
+			t.Fatalf("GetAccounts after three 503s: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("three retries took %s, want each capped at 20ms", elapsed)
+		}
+		if n := countRequests(srv, http.MethodGet, "/accounts"); n != 4 {
+			t.Errorf("got %d requests, want 4", n)
+		}
+	})
+
+	t.Run("shared budget", func(t *testing.T) {
+		srv, client := newServer(t, api.WithRetryPolicy(api.RetryPolicy{MaxRetries: 5, BaseDelay: time.Millisecond, Budget: 2}))
+		srv.Inject(uptest.Fault{Path: "/accounts", Status: http.StatusServiceUnavailable})
+
+		var apiErr *api.Error
+		for i, want := range []int{3, 1} {
+			before := countRequests(srv, http.MethodGet, "/accounts")
+			_, err := client.GetAccounts(ctx, api.AccountFilter{})
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("call %d: error = %v, want 503", i+1, err)
+			}
+			if n := countRequests(srv, http.MethodGet, "/accounts") - before; n != want {
+				t.Errorf("call %d: got %d requests, want %d", i+1, n, want)
+			}
+		}
+	})
+
+	t.Run("POST", func(t *testing.T) {
+		srv, client := newServer(t, api.WithRetryPolicy(api.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond}))
+
+		// The webhook may have been created before the server failed
+		srv.Inject(uptest.Fault{Method: http.MethodPost, Path: "/webhooks", Status: http.StatusServiceUnavailable, Times: 1})
+		var apiErr *api.Error
+		_, err := client.CreateWebhook(ctx, "https://example.com/hook", "")
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("error = %v, want 503", err)
+		}
+		if n := countRequests(srv, http.MethodPost, "/webhooks"); n != 1 {
+			t.Errorf("got %d POSTs after a 503, want 1", n)
+		}
+
+		// A rate-limited request was never processed
+		srv.Inject(uptest.Fault{Method: http.MethodPost, Path: "/webhooks", Status: http.StatusTooManyRequests, Times: 1})
+		if _, err := client.CreateWebhook(ctx, "https://example.com/hook", ""); err != nil {
+			t.Errorf("CreateWebhook after a 429: %v", err)
+		}
+		if n := countRequests(srv, http.MethodPost, "/webhooks"); n != 3 {
+			t.Errorf("got %d POSTs in total, want 3", n)
+		}
+	})
+}
+
+func TestEmit(t *testing.T) {
+	srv, client := newServer(t)
+	ctx := context.Background()
+
+	type delivery struct {
+		body      []byte
+		signature string
+	}
+	deliveries := make(chan delivery, 1)
+	status := http.StatusOK
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- delivery{body, r.Header.Get(webhook.SignatureHeader)}
+		w.WriteHeader(status)
+	}))
+	defer receiver.Close()
+
+	wh, err := client.CreateWebhook(ctx, receiver.URL, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte(*wh.Attributes.SecretKey)
+
+	event, err := srv.Emit(ctx, wh.ID, models.EventTransactionCreated, "tx-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := <-deliveries
+	if !webhook.Verify(secret, d.body, d.signature) {
+		t.Fatalf("delivery signature %q does not verify", d.signature)
+	}
+	if webhook.Verify([]byte("wrong secret"), d.body, d.signature) {
+		t.Error("delivery verifies with the wrong secret")
+	}
+	decoded, err := webhook.Decode(secret, d.body, d.signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ID != event.ID || decoded.Attributes.EventType != models.EventTransactionCreated || decoded.Relations.Transaction.Data.ID != "tx-01" {
+		t.Errorf("delivered event = %+v, want %s for tx-01", decoded, event.ID)
+	}
+
+	status = http.StatusInternalServerError
+	if _, err := client.PingWebhook(ctx, wh.ID); err != nil {
+		t.Fatal(err)
+	}
+	<-deliveries
+
+	logs, err := client.ListWebhookLogs(ctx, wh.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range logs {
+		got = append(got, l.Attributes.DeliveryStatus)
+	}
+	if want := []string{"BAD_RESPONSE_CODE", "DELIVERED"}; !slices.Equal(got, want) {
+		t.Errorf("delivery logs = %v, want %v", got, want)
+	}
+
+	if _, err := srv.Emit(ctx, "no-such-webhook", models.EventPing, ""); err == nil {
+		t.Error("Emit to an unknown webhook succeeded")
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	srv, client := newServer(t)
+	ctx := context.Background()
+
+	attachments, err := client.ListAttachments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attachments) != 1 || attachments[0].Relations.Transaction.Data.ID != "tx-13" {
+		t.Fatalf("attachments = %+v, want the receipt of tx-13", attachments)
+	}
+	tx, _ := srv.Transaction("tx-13")
+	if tx.Relations.Attachment.Data == nil || tx.Relations.Attachment.Data.ID != attachments[0].ID {
+		t.Errorf("tx-13 attachment relationship = %+v", tx.Relations.Attachment.Data)
+	}
+
+	var file bytes.Buffer
+	n, err := client.DownloadAttachment(ctx, attachments[0], &file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 || int64(file.Len()) != n || !bytes.HasPrefix(file.Bytes(), []byte{0xff, 0xd8}) {
+		t.Errorf("downloaded %d bytes: %x", n, file.Bytes())
+	}
+
+	// The signature covers the attachment and the expiry
+	fileURL, _ := url.Parse(*attachments[0].Attributes.FileURL)
+	q := fileURL.Query()
+	q.Set("expires", "9999999999")
+	fileURL.RawQuery = q.Encode()
+	resp, err := http.Get(fileURL.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("tampered file URL: status %d, want 403", resp.StatusCode)
+	}
+
+	if _, err := client.GetAttachment(ctx, "no-such-attachment"); err == nil {
+		t.Error("GetAttachment of an unknown attachment succeeded")
+	}
+}
+
+func TestDownloadRetries(t *testing.T) {
+	srv, client := newServer(t, api.WithRetryPolicy(api.RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond}))
+	ctx := context.Background()
+	attachment, err := client.GetAttachment(ctx, "att-receipt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.Inject(uptest.Fault{Path: "/files", Status: http.StatusServiceUnavailable, Times: 2})
+	var file bytes.Buffer
+	if _, err := client.DownloadAttachment(ctx, attachment, &file); err != nil {
+		t.Fatalf("DownloadAttachment after two 503s: %v", err)
+	}
+	if !bytes.HasPrefix(file.Bytes(), []byte{0xff, 0xd8}) {
+		t.Errorf("downloaded %x", file.Bytes())
+	}
+
+	// A URL refused before its stated expiry is signed again, not retried
+	expired := attachment
+	u, _ := url.Parse(*attachment.Attributes.FileURL)
+	q := u.Query()
+	q.Set("signature", "expired")
+	u.RawQuery = q.Encode()
+	fileURL := u.String()
+	expired.Attributes.FileURL = &fileURL
+	before := len(srv.Requests())
+	file.Reset()
+	if _, err := client.DownloadAttachment(ctx, expired, &file); err != nil {
+		t.Fatalf("DownloadAttachment with an expired URL: %v", err)
+	}
+	if !bytes.HasPrefix(file.Bytes(), []byte{0xff, 0xd8}) {
+		t.Errorf("downloaded %x", file.Bytes())
+	}
+	if got := srv.Requests()[before:]; len(got) != 3 || !strings.Contains(got[1], "/attachments/att-receipt") {
+		t.Errorf("requests = %v, want a refused download, a refetch and a download", got)
+	}
+
+	// A fresh URL that is refused too is not worth retrying
+	srv.Inject(uptest.Fault{Path: "/files", Status: http.StatusForbidden})
+	before = len(srv.Requests())
+	var apiErr *api.Error
+	_, err = client.DownloadAttachment(ctx, attachment, io.Discard)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("error = %v, want 403", err)
+	}
+	if n := len(srv.Requests()) - before; n != 3 {
+		t.Errorf("got %d requests, want 2 download attempts and a refetch", n)
+	}
+}
@@ -0,0 +1,218 @@
+package uptest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+	"upbank-cli/pkg/models"
+	"upbank-cli/pkg/webhook"
+)
+
+// Limits Up places on webhooks.
+const (
+	maxWebhooks           = 10
+	maxWebhookURL         = 300
+	maxWebhookDescription = 64
+)
+
+// deliveryTimeout bounds a single delivery to a webhook URL.
+const deliveryTimeout = 10 * time.Second
+
+func (s *Server) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	webhooks := slices.Clone(s.webhooks)
+	s.mu.Unlock()
+	paginate(s, w, r, webhooks)
+}
+
+func (s *Server) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Data struct {
+			Attributes struct {
+				URL         string  `json:"url"`
+				Description *string `json:"description"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	attrs := body.Data.Attributes
+	if u, err := url.Parse(attrs.URL); err != nil || !u.IsAbs() || len(attrs.URL) > maxWebhookURL {
+		writeError(w, http.StatusBadRequest, "Invalid Request Body", "data.attributes.url must be an absolute URL of at most 300 characters.", "")
+		return
+	}
+	if attrs.Description != nil && len(*attrs.Description) > maxWebhookDescription {
+		writeError(w, http.StatusBadRequest, "Invalid Request Body", "data.attributes.description must be at most 64 characters.", "")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.webhooks) >= maxWebhooks {
+		writeError(w, http.StatusUnprocessableEntity, "Too Many Webhooks", "At most 10 webhooks can be registered.", "")
+		return
+	}
+
+	secret := make([]byte, 32)
+	_, _ = rand.Read(secret)
+	key := hex.EncodeToString(secret)
+
+	var wh models.Webhook
+	wh.Type = "webhooks"
+	wh.ID = s.nextID("webhook")
+	wh.Attributes.URL = attrs.URL
+	wh.Attributes.Description = attrs.Description
+	wh.Attributes.CreatedAt = time.Now().Truncate(time.Second)
+	wh.Relations.Logs.Links.Related = s.BaseURL() + "/webhooks/" + wh.ID + "/logs"
+	wh.Links.Self = s.BaseURL() + "/webhooks/" + wh.ID
+	s.webhooks = append(s.webhooks, wh)
+	s.secrets[wh.ID] = key
+
+	// The secret key is only ever returned here
+	wh.Attributes.SecretKey = &key
+	writeJSON(w, http.StatusCreated, models.WebhookResponse{Data: wh})
+}
+
+func (s *Server) getWebhook(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.webhookIndex(r.PathValue("id"))
+	if i < 0 {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.WebhookResponse{Data: s.webhooks[i]})
+}
+
+func (s *Server) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := r.PathValue("id")
+	i := s.webhookIndex(id)
+	if i < 0 {
+		notFound(w)
+		return
+	}
+	s.webhooks = slices.Delete(s.webhooks, i, i+1)
+	delete(s.secrets, id)
+	delete(s.logs, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pingWebhook delivers a PING event to the webhook and returns the event.
+func (s *Server) pingWebhook(w http.ResponseWriter, r *http.Request) {
+	event, err := s.Emit(r.Context(), r.PathValue("id"), models.EventPing, "")
+	if err != nil {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusCreated, models.WebhookEventResponse{Data: event})
+}
+
+// listWebhookLogs lists delivery attempts, newest first.
+func (s *Server) listWebhookLogs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	id := r.PathValue("id")
+	if s.webhookIndex(id) < 0 {
+		s.mu.Unlock()
+		notFound(w)
+		return
+	}
+	logs := slices.Clone(s.logs[id])
+	s.mu.Unlock()
+	paginate(s, w, r, logs)
+}
+
+// Emit delivers an event of the given type to a webhook, signed with its
+// secret key, and logs the attempt. transactionID is required for
+// transaction events and ignored for PING. Only webhooks created through the
+// API have a secret key; deliveries to others are signed with an empty key.
+// The returned error only reports an unknown webhook; failed deliveries are
+// logged like Up does.
+func (s *Server) Emit(ctx context.Context, webhookID, eventType, transactionID string) (models.WebhookEvent, error) {
+	s.mu.Lock()
+	i := s.webhookIndex(webhookID)
+	if i < 0 {
+		s.mu.Unlock()
+		return models.WebhookEvent{}, fmt.Errorf("webhook not found: %s", webhookID)
+	}
+	target := s.webhooks[i].Attributes.URL
+	secret := s.secrets[webhookID]
+
+	var event models.WebhookEvent
+	event.Type = "webhook-events"
+	event.ID = s.nextID("event")
+	event.Attributes.EventType = eventType
+	event.Attributes.CreatedAt = time.Now().Truncate(time.Second)
+	event.Relations.Webhook.Data.Type = "webhooks"
+	event.Relations.Webhook.Data.ID = webhookID
+	event.Relations.Webhook.Links.Related = s.BaseURL() + "/webhooks/" + webhookID
+	if eventType != models.EventPing {
+		_ = json.Unmarshal([]byte(`{"transaction": {}}`), &event.Relations)
+		event.Relations.Transaction.Data.Type = "transactions"
+		event.Relations.Transaction.Data.ID = transactionID
+		event.Relations.Transaction.Links.Related = s.BaseURL() + "/transactions/" + transactionID
+	}
+	s.mu.Unlock()
+
+	// Deliver without holding the lock, as the receiver may well call back
+	// into the API to fetch the transaction
+	body, _ := json.Marshal(models.WebhookEventResponse{Data: event})
+	entry := s.deliver(ctx, target, []byte(secret), body)
+	entry.Relations.WebhookEvent.Data.Type = "webhook-events"
+	entry.Relations.WebhookEvent.Data.ID = event.ID
+
+	s.mu.Lock()
+	entry.ID = s.nextID("log")
+	s.logs[webhookID] = append([]models.WebhookDeliveryLog{entry}, s.logs[webhookID]...)
+	s.mu.Unlock()
+	return event, nil
+}
+
+// deliver posts body to target and describes the attempt as a delivery log.
+func (s *Server) deliver(ctx context.Context, target string, secret, body []byte) models.WebhookDeliveryLog {
+	var entry models.WebhookDeliveryLog
+	entry.Type = "webhook-delivery-logs"
+	entry.Attributes.Request.Body = string(body)
+	entry.Attributes.CreatedAt = time.Now().Truncate(time.Second)
+	entry.Attributes.DeliveryStatus = "UNDELIVERABLE"
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deliveryTimeout)
+	defer cancel()
+	req, err := webhook.NewRequest(ctx, target, secret, body)
+	if err != nil {
+		return entry
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return entry
+	}
+	defer resp.Body.Close()
+	var respBody bytes.Buffer
+	_, _ = io.Copy(&respBody, io.LimitReader(resp.Body, 4096))
+
+	_ = json.Unmarshal([]byte(`{"response": {}}`), &entry.Attributes)
+	entry.Attributes.Response.StatusCode = resp.StatusCode
+	entry.Attributes.Response.Body = respBody.String()
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		entry.Attributes.DeliveryStatus = "DELIVERED"
+	} else {
+		entry.Attributes.DeliveryStatus = "BAD_RESPONSE_CODE"
+	}
+	return entry
+}
+
+// webhookIndex returns the index of a webhook, or -1. The caller must hold
+// s.mu.
+func (s *Server) webhookIndex(id string) int {
+	return slices.IndexFunc(s.webhooks, func(wh models.Webhook) bool { return wh.ID == id })
+}
@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LedgerEntry records that an event was acknowledged by a local receiver.
+type LedgerEntry struct {
+	EventID      string    `json:"eventId"`
+	WebhookID    string    `json:"webhookId,omitempty"`
+	EventType    string    `json:"eventType"`
+	Acknowledged time.Time `json:"acknowledgedAt"`
+}
+
+// Ledger is an append-only record of the events a local receiver has
+// acknowledged, stored as one JSON object per line. It is safe for
+// concurrent use.
+type Ledger struct {
+	mu   sync.Mutex
+	path string
+	seen map[string]bool
+}
+
+// OpenLedger reads the ledger at path. A missing file yields an empty ledger
+// that is created on the first Record.
+func OpenLedger(path string) (*Ledger, error) {
+	l := &Ledger{path: path, seen: make(map[string]bool)}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening delivery ledger: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry LedgerEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A torn final line from an interrupted write is not fatal
+			continue
+		}
+		l.seen[entry.EventID] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading delivery ledger: %v", err)
+	}
+	return l, nil
+}
+
+// Has reports whether the event has been acknowledged.
+func (l *Ledger) Has(eventID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seen[eventID]
+}
+
+// Record marks an event as acknowledged. Events already in the ledger are
+// not written again.
+func (l *Ledger) Record(entry LedgerEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.seen[entry.EventID] {
+		return nil
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return fmt.Errorf("error writing delivery ledger: %v", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error writing delivery ledger: %v", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing delivery ledger: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error writing delivery ledger: %v", err)
+	}
+	l.seen[entry.EventID] = true
+	return nil
+}
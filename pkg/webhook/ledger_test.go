@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLedger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "deliveries.jsonl")
+	l, err := OpenLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Has("evt-1") {
+		t.Fatal("empty ledger has evt-1")
+	}
+
+	entry := LedgerEntry{EventID: "evt-1", WebhookID: "wh-1", EventType: "PING", Acknowledged: time.Now()}
+	for range 2 {
+		if err := l.Record(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Record(LedgerEntry{EventID: "evt-2", EventType: "PING", Acknowledged: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if !l.Has("evt-1") || !l.Has("evt-2") {
+		t.Error("recorded events missing from the ledger")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(data, []byte("\n")); n != 2 {
+		t.Errorf("ledger has %d lines, want 2 with the duplicate dropped:\n%s", n, data)
+	}
+
+	// Reopening reads the recorded events, ignoring a torn final line
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"eventId":"evt-3"`)
+	f.Close()
+
+	reopened, err := OpenLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reopened.Has("evt-1") || !reopened.Has("evt-2") {
+		t.Error("reopened ledger lost recorded events")
+	}
+	if reopened.Has("evt-3") {
+		t.Error("reopened ledger has the torn evt-3")
+	}
+	before, _ := os.ReadFile(path)
+	if err := reopened.Record(entry); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, before) {
+		t.Errorf("duplicate evt-1 written after reopening:\n%s", after)
+	}
+}
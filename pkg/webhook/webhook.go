@@ -0,0 +1,133 @@
+// Package webhook receives and sends Up webhook deliveries. Up signs every
+// delivery with the webhook's secret key so that receivers can check it
+// really came from Up.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"upbank-cli/pkg/models"
+)
+
+// SignatureHeader is the header Up puts the delivery signature in.
+const SignatureHeader = "X-Up-Authenticity-Signature"
+
+// maxBodySize bounds the size of a delivery read by Handler.
+const maxBodySize = 1 << 20
+
+// ErrInvalidSignature is returned when a delivery's signature does not match
+// its body.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign returns the hex encoded HMAC-SHA256 of body keyed with secret, as sent
+// by Up in SignatureHeader.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the valid signature of body. The
+// comparison takes constant time.
+func Verify(secret, body []byte, signature string) bool {
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// Decode verifies the signature of a raw delivery body and decodes the event
+// it carries.
+func Decode(secret, body []byte, signature string) (models.WebhookEvent, error) {
+	if !Verify(secret, body, signature) {
+		return models.WebhookEvent{}, ErrInvalidSignature
+	}
+	var payload models.WebhookEventResponse
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return models.WebhookEvent{}, fmt.Errorf("error decoding webhook event: %v", err)
+	}
+	return payload.Data, nil
+}
+
+// NewRequest builds a signed delivery of body to url, exactly as Up would
+// send it. It is used to replay events and to test receivers locally.
+func NewRequest(ctx context.Context, url string, secret, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(secret, body))
+	return req, nil
+}
+
+// Deliver sends a signed delivery of body to url and returns an error unless
+// the receiver acknowledges it with a 2xx status.
+func Deliver(ctx context.Context, client *http.Client, url string, secret, body []byte) error {
+	req, err := NewRequest(ctx, url, secret, body)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("receiver responded with %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// EventFunc handles a verified webhook event. Returning an error makes the
+// delivery fail, so that Up retries it later.
+type EventFunc func(ctx context.Context, event models.WebhookEvent) error
+
+// Handler returns an http.Handler that accepts Up deliveries, rejects those
+// with a missing or bad signature, and passes the decoded event to fn.
+func Handler(secret []byte, fn EventFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// The signature covers the raw bytes, so read them before decoding
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+		if err != nil {
+			http.Error(w, "error reading body", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		event, err := Decode(secret, body, r.Header.Get(SignatureHeader))
+		if errors.Is(err, ErrInvalidSignature) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := fn(r.Context(), event); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
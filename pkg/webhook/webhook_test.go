@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"upbank-cli/pkg/models"
+)
+
+const testEvent = `{"data":{"type":"webhook-events","id":"evt-1","attributes":{"eventType":"TRANSACTION_CREATED","createdAt":"2024-01-15T09:00:00+11:00"},"relationships":{"webhook":{"data":{"type":"webhooks","id":"wh-1"}},"transaction":{"data":{"type":"transactions","id":"tx-1"}}}}}`
+
+func TestVerify(t *testing.T) {
+	secret := []byte("s3cr3t")
+	body := []byte(testEvent)
+	signature := Sign(secret, body)
+
+	tests := []struct {
+		name      string
+		secret    []byte
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{"valid", secret, body, signature, true},
+		{"upper case hex", secret, body, strings.ToUpper(signature), true},
+		{"tampered body", secret, []byte(strings.Replace(testEvent, "tx-1", "tx-2", 1)), signature, false},
+		{"bad hex", secret, body, "not-hex", false},
+		{"truncated", secret, body, signature[:len(signature)-2], false},
+		{"missing", secret, body, "", false},
+		{"wrong secret", []byte("other"), body, signature, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, tt.body, tt.signature); got != tt.want {
+				t.Errorf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	secret := []byte("s3cr3t")
+
+	event, err := Decode(secret, []byte(testEvent), Sign(secret, []byte(testEvent)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.ID != "evt-1" || event.Attributes.EventType != models.EventTransactionCreated {
+		t.Errorf("event = %s %s", event.ID, event.Attributes.EventType)
+	}
+	if event.Relations.Webhook.Data.ID != "wh-1" || event.Relations.Transaction == nil || event.Relations.Transaction.Data.ID != "tx-1" {
+		t.Errorf("relationships = %+v", event.Relations)
+	}
+
+	if _, err := Decode(secret, []byte(testEvent), "00"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("bad signature: error = %v, want ErrInvalidSignature", err)
+	}
+	notJSON := []byte("not json")
+	if _, err := Decode(secret, notJSON, Sign(secret, notJSON)); err == nil || errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("signed invalid JSON: error = %v, want a decoding error", err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	secret := []byte("s3cr3t")
+	body := []byte(testEvent)
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		signature  string
+		fnErr      error
+		wantStatus int
+		wantCalled bool
+	}{
+		{"valid", http.MethodPost, testEvent, Sign(secret, body), nil, http.StatusOK, true},
+		{"bad signature", http.MethodPost, testEvent, Sign([]byte("other"), body), nil, http.StatusUnauthorized, false},
+		{"missing signature", http.MethodPost, testEvent, "", nil, http.StatusUnauthorized, false},
+		{"invalid JSON", http.MethodPost, "{", Sign(secret, []byte("{")), nil, http.StatusBadRequest, false},
+		{"handler error", http.MethodPost, testEvent, Sign(secret, body), errors.New("boom"), http.StatusInternalServerError, true},
+		{"GET", http.MethodGet, "", "", nil, http.StatusMethodNotAllowed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			h := Handler(secret, func(ctx context.Context, event models.WebhookEvent) error {
+				called = true
+				if event.ID != "evt-1" {
+					t.Errorf("event ID = %s", event.ID)
+				}
+				return tt.fnErr
+			})
+
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			if tt.signature != "" {
+				req.Header.Set(SignatureHeader, tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCalled)
+			}
+		})
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	secret := []byte("s3cr3t")
+	var got models.WebhookEvent
+	srv := httptest.NewServer(Handler(secret, func(ctx context.Context, event models.WebhookEvent) error {
+		got = event
+		return nil
+	}))
+	defer srv.Close()
+
+	if err := Deliver(context.Background(), srv.Client(), srv.URL, secret, []byte(testEvent)); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "evt-1" {
+		t.Errorf("delivered event = %+v", got)
+	}
+
+	err := Deliver(context.Background(), srv.Client(), srv.URL, []byte("other"), []byte(testEvent))
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("wrong secret: error = %v, want a 401", err)
+	}
+}